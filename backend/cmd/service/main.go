@@ -3,22 +3,49 @@ package main
 import (
 	"database/sql"
 	"free_toilet_map/cmd/db"
+	"free_toilet_map/toilet/challenge"
 	"free_toilet_map/toilet/endpoint"
+	"free_toilet_map/toilet/mail"
+	"free_toilet_map/toilet/oidc"
 	"free_toilet_map/toilet/repository"
+	"free_toilet_map/toilet/screening"
 	"free_toilet_map/toilet/service"
+	"free_toilet_map/toilet/token"
 	"free_toilet_map/toilet/transport"
+	"free_toilet_map/toilet/validation"
 	"log"
 	"net/http"
+	"os"
 )
 
-func initService(db *sql.DB) (endpoint.Endpoints, error) {
+func initService(db *sql.DB) (*service.Service, endpoint.Endpoints, error) {
+    tokens, err := token.NewServiceFromEnv()  // Load the JWT signing and verification keys
+    if err != nil {
+        return nil, endpoint.Endpoints{}, err
+    }
+
+    providers, err := oidc.NewProvidersFromEnv()  // Identity providers for "Sign in with..."
+    if err != nil {
+        return nil, endpoint.Endpoints{}, err
+    }
+
     repo := repository.NewPostgresRepoWithDB(db)
-    svc := service.NewService(*repo)  // Initialize the service with the repository
-    return endpoint.MakeEndpoints(*svc), nil  // Dereference svc here to pass the value to MakeEndpoints
+    svc := service.NewService(*repo, tokens, mail.NewMailerFromEnv())  // Initialize the service with the repository
+    svc.Providers = providers
+    svc.Passwords = validation.NewPasswordPolicyFromEnv()  // Optional breached password list
+    svc.Screening = screening.NewDefaultPipeline(svc.RecentContent)  // Spam and profanity filter for toilets, reviews and comments
+    svc.CommentHooks = append(svc.CommentHooks, svc.ScreenComment)  // Comments go through the filter and the limits of new accounts too
+    svc.Approval = service.NewToiletApprovalFromEnv()  // How many confirmations approve a toilet, and who sees pending ones
+    svc.Anomalies.ExcludeFlagged = os.Getenv("EXCLUDE_FLAGGED_REVIEWS") != "false"  // Keep suspected review bombing out of ratings
+    svc.Challenges = challenge.NewIssuerFromEnv()  // Proof-of-work challenges for registration and login
+    if appURL := os.Getenv("APP_URL"); appURL != "" {
+        svc.AppURL = appURL  // Frontend address used in email links
+    }
+    return svc, endpoint.MakeEndpoints(*svc), nil  // Dereference svc here to pass the value to MakeEndpoints
 }
 
-func initHTTPHandler(eps endpoint.Endpoints) http.Handler {
-    return transport.NewHTTPHandler(eps)
+func initHTTPHandler(svc *service.Service, eps endpoint.Endpoints) http.Handler {
+    return transport.NewHTTPHandler(eps, svc)
 }
 
 func main() {
@@ -33,11 +60,11 @@ func main() {
     db.RunMigrations(dbConn)
 
     // Initialize service and HTTP handler
-    eps, err := initService(dbConn)
+    svc, eps, err := initService(dbConn)
     if err != nil {
         log.Fatalf("Error initializing service: %v", err)
     }
-    handler := initHTTPHandler(eps)
+    handler := initHTTPHandler(svc, eps)
 
     // Start the HTTP server
     log.Println("🚀 Listening on :8080")
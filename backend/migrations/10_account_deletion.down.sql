@@ -0,0 +1,11 @@
+ALTER TABLE review_comments DROP CONSTRAINT IF EXISTS review_comments_user_id_fkey;
+ALTER TABLE review_comments ADD CONSTRAINT review_comments_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_user_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_founder_id_fkey;
+ALTER TABLE toilets ADD CONSTRAINT toilets_founder_id_fkey
+    FOREIGN KEY (founder_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,19 @@
+-- Placeholder owner of community content whose author deleted their account.
+-- The password is not a valid bcrypt hash, so nobody can sign in as it.
+INSERT INTO users (username, password, display_name)
+VALUES ('deleted_user', '!', 'Deleted user')
+ON CONFLICT (username) DO NOTHING;
+
+-- Deleting a user must not silently delete the toilets, reviews and
+-- comments they contributed; the service reassigns them first.
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_founder_id_fkey;
+ALTER TABLE toilets ADD CONSTRAINT toilets_founder_id_fkey
+    FOREIGN KEY (founder_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_user_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE review_comments DROP CONSTRAINT IF EXISTS review_comments_user_id_fkey;
+ALTER TABLE review_comments ADD CONSTRAINT review_comments_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
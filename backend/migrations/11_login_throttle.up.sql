@@ -0,0 +1,13 @@
+-- Failed login attempts, counted per attempted username and per client IP.
+-- Usernames are tracked whether or not the account exists, so lockouts do
+-- not reveal which accounts are registered.
+CREATE TABLE IF NOT EXISTS login_throttle (
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (kind, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttle_last_failure ON login_throttle(last_failure_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_username_key;
+ALTER TABLE users DROP COLUMN IF EXISTS username_key;
@@ -0,0 +1,14 @@
+-- Skeleton of the username (see validation.UsernameKey) used to refuse
+-- names that look like an existing one. For existing users it is computed
+-- with the ASCII part of the mapping, which is all that older usernames use.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_key TEXT;
+UPDATE users SET username_key = translate(lower(username), '01i.-', 'oll__')
+WHERE username_key IS NULL;
+
+-- Older users whose names look alike keep them. The oldest of them holds
+-- the skeleton, which still refuses new look-alikes of all of them; the
+-- others get their ID appended so that the skeleton can be unique.
+UPDATE users u SET username_key = u.username_key || '#' || u.id
+WHERE EXISTS (SELECT 1 FROM users o WHERE o.username_key = u.username_key AND o.id < u.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_key ON users(username_key);
@@ -0,0 +1,7 @@
+-- Where and with what a session was started, so that users can recognise
+-- their devices and sign out the ones they no longer use
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP;
+
+UPDATE sessions SET last_seen_at = created_at WHERE last_seen_at IS NULL;
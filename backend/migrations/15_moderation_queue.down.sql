@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS user_bans;
+DROP TABLE IF EXISTS user_warnings;
+DROP TABLE IF EXISTS moderation_notes;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS moderation_items;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS status;
+ALTER TABLE toilets DROP COLUMN IF EXISTS status;
@@ -0,0 +1,73 @@
+-- Toilets and reviews can be hidden by moderators like comments already can
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'visible'
+    CONSTRAINT toilets_status_check CHECK (status IN ('visible', 'hidden'));
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'visible'
+    CONSTRAINT reviews_status_check CHECK (status IN ('visible', 'hidden'));
+
+-- One queue entry per reported piece of content. Reports on a target
+-- that already has an open item are added to it; once the item is
+-- resolved, a new report opens a new item.
+CREATE TABLE IF NOT EXISTS moderation_items (
+    id SERIAL PRIMARY KEY,
+    target_type TEXT NOT NULL CHECK (target_type IN ('toilet', 'review', 'comment', 'user')),
+    target_id INTEGER NOT NULL,
+    state TEXT NOT NULL DEFAULT 'open' CHECK (state IN ('open', 'actioned', 'dismissed')),
+    assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL DEFAULT '',
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_moderation_items_open_target
+    ON moderation_items(target_type, target_id) WHERE state = 'open';
+CREATE INDEX IF NOT EXISTS idx_moderation_items_state ON moderation_items(state, updated_at);
+
+-- A user can report the same item only once
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES moderation_items(id) ON DELETE CASCADE,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (item_id, reporter_id)
+);
+
+CREATE TABLE IF NOT EXISTS moderation_notes (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES moderation_items(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_notes_item ON moderation_notes(item_id);
+
+-- Warnings sent to users whose content was reported
+CREATE TABLE IF NOT EXISTS user_warnings (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_id INTEGER REFERENCES moderation_items(id) ON DELETE SET NULL,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_warnings_user ON user_warnings(user_id);
+
+-- Users who are not allowed to sign in. A ban stays in force until it
+-- expires or is lifted.
+CREATE TABLE IF NOT EXISTS user_bans (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_id INTEGER REFERENCES moderation_items(id) ON DELETE SET NULL,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    lifted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bans_user ON user_bans(user_id);
@@ -0,0 +1,7 @@
+UPDATE toilets SET status = 'hidden' WHERE status = 'pending';
+UPDATE reviews SET status = 'hidden' WHERE status = 'pending';
+
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_status_check;
+ALTER TABLE toilets ADD CONSTRAINT toilets_status_check CHECK (status IN ('visible', 'hidden'));
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_status_check;
+ALTER TABLE reviews ADD CONSTRAINT reviews_status_check CHECK (status IN ('visible', 'hidden'));
@@ -0,0 +1,6 @@
+-- Toilets and reviews held back by the content filter wait for a moderator
+-- as 'pending'
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_status_check;
+ALTER TABLE toilets ADD CONSTRAINT toilets_status_check CHECK (status IN ('visible', 'hidden', 'pending'));
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_status_check;
+ALTER TABLE reviews ADD CONSTRAINT reviews_status_check CHECK (status IN ('visible', 'hidden', 'pending'));
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_reports_reporter;
+DROP INDEX IF EXISTS idx_moderation_items_target_user;
+ALTER TABLE moderation_items DROP COLUMN IF EXISTS target_user_id;
+ALTER TABLE reports DROP COLUMN IF EXISTS weight;
+ALTER TABLE reviews DROP COLUMN IF EXISTS weight;
+DROP INDEX IF EXISTS toilets_founder_created_idx;
+ALTER TABLE toilets DROP COLUMN IF EXISTS created_at;
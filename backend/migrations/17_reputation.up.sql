@@ -0,0 +1,29 @@
+-- Toilets get a creation time so that contributions can be limited per day.
+-- When existing toilets were added is unknown, so theirs stays NULL rather
+-- than counting them all as added today.
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+ALTER TABLE toilets ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX IF NOT EXISTS toilets_founder_created_idx ON toilets (founder_id, created_at);
+
+-- Users who existed before accounts had a creation time got the time that
+-- column was added. Move it back to their earliest known activity, a review
+-- of one of their toilets standing in for the toilet itself, so the age of
+-- their account counts for their reputation.
+UPDATE users u SET created_at = LEAST(
+    u.created_at,
+    (SELECT MIN(r.created_at) FROM reviews r WHERE r.user_id = u.id),
+    (SELECT MIN(r.created_at) FROM reviews r JOIN toilets t ON t.id = r.toilet_id WHERE t.founder_id = u.id),
+    (SELECT MIN(c.created_at) FROM review_comments c WHERE c.user_id = u.id),
+    (SELECT MIN(l.created_at) FROM toilet_lists l WHERE l.user_id = u.id)
+);
+
+-- Reviews and reports count according to the reputation of their author
+-- at the time they were written
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS weight REAL NOT NULL DEFAULT 1;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS weight REAL NOT NULL DEFAULT 1;
+
+-- The author of content a moderator acted against. Kept separately since
+-- deleted content cannot be traced back to its author anymore.
+ALTER TABLE moderation_items ADD COLUMN IF NOT EXISTS target_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_moderation_items_target_user ON moderation_items(target_user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reporter ON reports(reporter_id);
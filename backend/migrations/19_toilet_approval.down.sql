@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS toilet_confirmations;
+DROP INDEX IF EXISTS idx_toilets_pending;
+ALTER TABLE toilets DROP COLUMN IF EXISTS approval_at;
+ALTER TABLE toilets DROP COLUMN IF EXISTS approval_by;
+ALTER TABLE toilets DROP COLUMN IF EXISTS approval_reason;
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_approval_check;
+ALTER TABLE toilets DROP COLUMN IF EXISTS approval;
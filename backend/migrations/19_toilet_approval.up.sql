@@ -0,0 +1,18 @@
+-- New toilets wait for approval by a moderator or by confirmations of
+-- other users. Toilets added before count as approved.
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS approval TEXT NOT NULL DEFAULT 'approved';
+ALTER TABLE toilets ALTER COLUMN approval SET DEFAULT 'pending';
+ALTER TABLE toilets DROP CONSTRAINT IF EXISTS toilets_approval_check;
+ALTER TABLE toilets ADD CONSTRAINT toilets_approval_check CHECK (approval IN ('pending', 'approved', 'rejected'));
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS approval_reason TEXT NOT NULL DEFAULT '';
+-- The moderator who decided, NULL for approval by the community
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS approval_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS approval_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_toilets_pending ON toilets(created_at) WHERE approval = 'pending';
+
+CREATE TABLE IF NOT EXISTS toilet_confirmations (
+    toilet_id INTEGER NOT NULL REFERENCES toilets(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (toilet_id, user_id)
+);
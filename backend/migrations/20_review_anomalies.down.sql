@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_reviews_user_created;
+DROP INDEX IF EXISTS idx_reviews_toilet_created;
+ALTER TABLE reviews DROP COLUMN IF EXISTS anomaly;
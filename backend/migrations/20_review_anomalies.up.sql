@@ -0,0 +1,6 @@
+-- Reviews that are part of a suspicious rating pattern ('burst' or
+-- 'spree'). Flagged reviews can be left out of ratings until a moderator
+-- has looked at them.
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS anomaly TEXT;
+CREATE INDEX IF NOT EXISTS idx_reviews_toilet_created ON reviews(toilet_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_created ON reviews(user_id, created_at);
@@ -0,0 +1,6 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS location_accuracy;
+ALTER TABLE reviews DROP COLUMN IF EXISTS location_distance;
+ALTER TABLE reviews DROP COLUMN IF EXISTS location_check;
+ALTER TABLE toilets DROP COLUMN IF EXISTS location_accuracy;
+ALTER TABLE toilets DROP COLUMN IF EXISTS location_distance;
+ALTER TABLE toilets DROP COLUMN IF EXISTS location_check;
@@ -0,0 +1,10 @@
+-- Where the device of the author was when a toilet or review was submitted,
+-- compared with the toilet: 'near' or 'remote', the distance and the
+-- accuracy of the device location in meters. 'unknown' if the client sent
+-- no location, NULL for submissions from before the check.
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS location_check TEXT;
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS location_distance REAL;
+ALTER TABLE toilets ADD COLUMN IF NOT EXISTS location_accuracy REAL;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS location_check TEXT;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS location_distance REAL;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS location_accuracy REAL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ban_appeals;
+DROP VIEW IF EXISTS shadow_banned_users;
+ALTER TABLE user_bans DROP COLUMN IF EXISTS lifted_by;
+ALTER TABLE user_bans DROP CONSTRAINT IF EXISTS user_bans_kind_check;
+ALTER TABLE user_bans DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,30 @@
+-- Bans come in three kinds: 'suspension' for a period, 'ban' for good and
+-- 'shadow_ban', which leaves the account working but hides its content
+-- from everyone else. Bans made before count by their expiry.
+ALTER TABLE user_bans ADD COLUMN IF NOT EXISTS kind TEXT;
+UPDATE user_bans SET kind = CASE WHEN expires_at IS NULL THEN 'ban' ELSE 'suspension' END WHERE kind IS NULL;
+ALTER TABLE user_bans ALTER COLUMN kind SET NOT NULL;
+ALTER TABLE user_bans DROP CONSTRAINT IF EXISTS user_bans_kind_check;
+ALTER TABLE user_bans ADD CONSTRAINT user_bans_kind_check CHECK (kind IN ('suspension', 'ban', 'shadow_ban'));
+-- The moderator who lifted the ban, by hand or by accepting an appeal
+ALTER TABLE user_bans ADD COLUMN IF NOT EXISTS lifted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- Users whose content only they can see. Read queries filter on it.
+CREATE OR REPLACE VIEW shadow_banned_users AS
+    SELECT DISTINCT user_id FROM user_bans
+    WHERE kind = 'shadow_ban' AND lifted_at IS NULL
+        AND (expires_at IS NULL OR expires_at > (NOW() AT TIME ZONE 'UTC'));
+
+-- A user can appeal each ban once
+CREATE TABLE IF NOT EXISTS ban_appeals (
+    id SERIAL PRIMARY KEY,
+    ban_id INTEGER NOT NULL UNIQUE REFERENCES user_bans(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'open' CHECK (state IN ('open', 'accepted', 'rejected')),
+    response TEXT NOT NULL DEFAULT '',
+    decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_ban_appeals_open ON ban_appeals(created_at) WHERE state = 'open';
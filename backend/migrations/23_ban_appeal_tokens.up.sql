@@ -0,0 +1,5 @@
+-- Users who sign in while banned get a single-use token to appeal with,
+-- since accounts created through a provider have no password to give
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('password_reset', 'email_verification', 'ban_appeal'));
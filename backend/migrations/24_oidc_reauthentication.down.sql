@@ -0,0 +1,6 @@
+DELETE FROM user_tokens WHERE purpose = 'reauthentication';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('password_reset', 'email_verification', 'ban_appeal'));
+
+ALTER TABLE oidc_states DROP COLUMN IF EXISTS reauth_user_id;
@@ -0,0 +1,8 @@
+-- Signed-in users can confirm who they are through a linked provider
+-- instead of with their password, which accounts created through a
+-- provider do not know. The flow ends with a short-lived single-use token.
+ALTER TABLE oidc_states ADD COLUMN IF NOT EXISTS reauth_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('password_reset', 'email_verification', 'ban_appeal', 'reauthentication'));
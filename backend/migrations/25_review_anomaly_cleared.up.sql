@@ -0,0 +1,3 @@
+-- Reviews a moderator found fine. They are not flagged again when the
+-- next review of the toilet or of their author runs the checks.
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS anomaly_cleared_at TIMESTAMP;
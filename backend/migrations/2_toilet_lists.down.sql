@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS toilet_list_items;
+DROP TABLE IF EXISTS toilet_lists;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS toilet_lists (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    visibility TEXT NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'unlisted', 'public')),
+    slug TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS toilet_lists_user_id_idx ON toilet_lists (user_id);
+
+CREATE TABLE IF NOT EXISTS toilet_list_items (
+    list_id INTEGER NOT NULL REFERENCES toilet_lists(id) ON DELETE CASCADE,
+    toilet_id INTEGER NOT NULL REFERENCES toilets(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    note TEXT NOT NULL DEFAULT '',
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, toilet_id)
+);
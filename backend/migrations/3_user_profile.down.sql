@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS reviews_user_id_idx;
+DROP INDEX IF EXISTS toilets_founder_id_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS preferred_language,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_comments;
+DROP TABLE IF EXISTS toilet_owners;
@@ -0,0 +1,19 @@
+-- Users confirmed to run the venue a toilet belongs to
+CREATE TABLE IF NOT EXISTS toilet_owners (
+    toilet_id INTEGER NOT NULL REFERENCES toilets(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    verified_at TIMESTAMP,
+    PRIMARY KEY (toilet_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS review_comments (
+    id SERIAL PRIMARY KEY,
+    review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'visible' CHECK (status IN ('visible', 'hidden')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS review_comments_review_id_idx ON review_comments (review_id);
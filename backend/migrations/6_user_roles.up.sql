@@ -0,0 +1,5 @@
+-- The first admin has to be appointed by hand:
+--   UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+        CHECK (role IN ('user', 'trusted', 'moderator', 'admin'));
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_tokens;
+DROP INDEX IF EXISTS users_email_key;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_verified_at,
+    DROP COLUMN IF EXISTS email;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,          -- first characters of the key, shown to tell keys apart
+    key_hash TEXT NOT NULL UNIQUE, -- SHA-256 of the full key
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    daily_quota INTEGER NOT NULL DEFAULT 1000,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+-- Requests per key and UTC day, used to enforce daily_quota
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
@@ -0,0 +1,110 @@
+// Package apperr defines the kinds of failure the service reports to
+// clients. Each kind is answered with its own HTTP status code by the
+// transport; any other error is an internal error whose details are logged
+// but not shown.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind classifies an error for clients
+type Kind int
+
+const (
+	// KindInternal is any error not classified otherwise
+	KindInternal Kind = iota
+	// KindInvalid is a malformed request or one that breaks a rule
+	KindInvalid
+	// KindUnauthorized is a request without valid credentials
+	KindUnauthorized
+	// KindForbidden is a request the user is not allowed to make
+	KindForbidden
+	// KindNotFound is a request for something that does not exist, or that
+	// the user may not know about
+	KindNotFound
+	// KindConflict is a request that clashes with the current state, like
+	// a name that is already taken
+	KindConflict
+	// KindTooManyRequests is a request over a rate limit
+	KindTooManyRequests
+)
+
+var statusCodes = map[Kind]int{
+	KindInternal:        http.StatusInternalServerError,
+	KindInvalid:         http.StatusBadRequest,
+	KindUnauthorized:    http.StatusUnauthorized,
+	KindForbidden:       http.StatusForbidden,
+	KindNotFound:        http.StatusNotFound,
+	KindConflict:        http.StatusConflict,
+	KindTooManyRequests: http.StatusTooManyRequests,
+}
+
+// Error is an error of a kind with a message meant for clients. Err is the
+// underlying cause, if any, which is kept for logs.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode implements go-kit's StatusCoder
+func (e *Error) StatusCode() int {
+	return statusCodes[e.Kind]
+}
+
+// Invalid returns an error for a malformed request or one that breaks a rule
+func Invalid(msg string) error {
+	return &Error{Kind: KindInvalid, Message: msg}
+}
+
+// Unauthorized returns an error for missing or wrong credentials
+func Unauthorized(msg string) error {
+	return &Error{Kind: KindUnauthorized, Message: msg}
+}
+
+// Forbidden returns an error for a request the user is not allowed to make
+func Forbidden(msg string) error {
+	return &Error{Kind: KindForbidden, Message: msg}
+}
+
+// NotFound returns an error for something that does not exist
+func NotFound(msg string) error {
+	return &Error{Kind: KindNotFound, Message: msg}
+}
+
+// Conflict returns an error for a request that clashes with the current
+// state
+func Conflict(msg string) error {
+	return &Error{Kind: KindConflict, Message: msg}
+}
+
+// TooManyRequests returns an error for a request over a rate limit
+func TooManyRequests(msg string) error {
+	return &Error{Kind: KindTooManyRequests, Message: msg}
+}
+
+// Wrap classifies err as kind with a message for clients
+func Wrap(kind Kind, msg string, err error) error {
+	return &Error{Kind: kind, Message: msg, Err: err}
+}
+
+// KindOf returns the kind of the first Error in err's chain, or
+// KindInternal if there is none
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,9 @@
+package auth
+
+// Claims are the identity carried by a verified access token
+type Claims struct {
+	UserID    int
+	SessionID string
+	Role      Role
+	APIKeyID  int // set when the request was authenticated with an API key
+}
@@ -6,7 +6,15 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey    contextKey = "user_id"
+	sessionIDKey contextKey = "session_id"
+	roleKey      contextKey = "role"
+	apiKeyIDKey  contextKey = "api_key_id"
+	clientIPKey  contextKey = "client_ip"
+	userAgentKey contextKey = "user_agent"
+	requestIDKey contextKey = "request_id"
+)
 
 // WithUserID adds the user ID to the request context
 func WithUserID(ctx context.Context, userID int) context.Context {
@@ -18,3 +26,78 @@ func GetUserID(ctx context.Context) (int, bool) {
 	userID, ok := ctx.Value(userIDKey).(int)
 	return userID, ok
 }
+
+// WithSessionID adds the ID of the session the access token belongs to
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// GetSessionID retrieves the session ID from the request context
+func GetSessionID(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey).(string)
+	return sessionID, ok
+}
+
+// WithRole adds the user's role to the request context
+func WithRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// GetRole retrieves the user's role from the request context. Requests
+// without a role are treated as regular users.
+func GetRole(ctx context.Context) Role {
+	if role, ok := ctx.Value(roleKey).(Role); ok {
+		return role
+	}
+	return RoleUser
+}
+
+// Can reports whether the user in the context has a permission
+func Can(ctx context.Context, p Permission) bool {
+	return GetRole(ctx).Can(p)
+}
+
+// WithAPIKeyID marks the request as authenticated with an API key
+func WithAPIKeyID(ctx context.Context, keyID int) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey, keyID)
+}
+
+// GetAPIKeyID retrieves the API key the request was authenticated with
+func GetAPIKeyID(ctx context.Context) (int, bool) {
+	keyID, ok := ctx.Value(apiKeyIDKey).(int)
+	return keyID, ok
+}
+
+// WithClientIP adds the address of the client that sent the request
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// GetClientIP retrieves the client address from the request context. It is
+// empty if the transport did not record one.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// WithUserAgent adds the client's User-Agent header to the request context
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey, userAgent)
+}
+
+// GetUserAgent retrieves the client's User-Agent from the request context
+func GetUserAgent(ctx context.Context) string {
+	userAgent, _ := ctx.Value(userAgentKey).(string)
+	return userAgent
+}
+
+// WithRequestID adds the ID the request is logged and audited under
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// GetRequestID retrieves the request ID from the request context
+func GetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
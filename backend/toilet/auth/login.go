@@ -0,0 +1,19 @@
+package auth
+
+import "free_toilet_map/toilet/apperr"
+
+var (
+	// ErrInvalidCredentials is returned for any wrong username or password,
+	// without telling which of the two was wrong
+	ErrInvalidCredentials = apperr.Unauthorized("invalid credentials")
+	// ErrLoginLocked is returned while the username or the client is locked
+	// out after too many failed logins
+	ErrLoginLocked = apperr.TooManyRequests("too many failed login attempts, try again later")
+	// ErrInvalidSecondFactor is returned for wrong or already used 2FA codes
+	ErrInvalidSecondFactor = apperr.Unauthorized("invalid two-factor code")
+	// ErrLoginChallengeExpired is returned when a 2FA login challenge is
+	// unknown, expired or out of attempts; the user has to sign in again
+	ErrLoginChallengeExpired = apperr.Unauthorized("login challenge expired, sign in again")
+	// ErrAccountBanned is returned when a banned user tries to sign in
+	ErrAccountBanned = apperr.Forbidden("this account has been banned")
+)
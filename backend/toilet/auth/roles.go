@@ -0,0 +1,62 @@
+package auth
+
+// Role is the access level of a user. Roles are ordered: every role has
+// all permissions of the roles below it.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleTrusted   Role = "trusted"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleUser:      0,
+	RoleTrusted:   1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Permission names an action that is restricted to some roles
+type Permission string
+
+const (
+	// PermModerateContent allows editing, hiding and deleting content of other users
+	PermModerateContent Permission = "content:moderate"
+	// PermManageRoles allows changing the role of other users
+	PermManageRoles Permission = "roles:manage"
+	// PermManageAPIKeys allows changing quotas and revoking API keys of any user
+	PermManageAPIKeys Permission = "apikeys:manage"
+	// PermManageAccounts allows lifting login lockouts and other account restrictions
+	PermManageAccounts Permission = "accounts:manage"
+	// PermViewAuditLog allows reading the audit log
+	PermViewAuditLog Permission = "audit:view"
+)
+
+// permissionRoles maps each permission to the lowest role that has it
+var permissionRoles = map[Permission]Role{
+	PermModerateContent: RoleModerator,
+	PermManageRoles:     RoleAdmin,
+	PermManageAPIKeys:   RoleAdmin,
+	PermManageAccounts:  RoleAdmin,
+	PermViewAuditLog:    RoleAdmin,
+}
+
+// ValidRole reports whether r is a known role
+func ValidRole(r Role) bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// AtLeast reports whether r is the same as or above other
+func (r Role) AtLeast(other Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[other]
+}
+
+// Can reports whether the role grants a permission
+func (r Role) Can(p Permission) bool {
+	min, ok := permissionRoles[p]
+	return ok && r.AtLeast(min)
+}
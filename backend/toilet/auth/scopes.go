@@ -0,0 +1,32 @@
+package auth
+
+import "free_toilet_map/toilet/apperr"
+
+// Scope limits what a third-party API key may be used for
+type Scope string
+
+const (
+	ScopeToiletsRead  Scope = "toilets:read"
+	ScopeToiletsWrite Scope = "toilets:write"
+	ScopeReviewsRead  Scope = "reviews:read"
+	ScopeReviewsWrite Scope = "reviews:write"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeToiletsRead:  true,
+	ScopeToiletsWrite: true,
+	ScopeReviewsRead:  true,
+	ScopeReviewsWrite: true,
+}
+
+// ValidScope reports whether s is a known scope
+func ValidScope(s Scope) bool {
+	return knownScopes[s]
+}
+
+var (
+	// ErrInsufficientScope is returned when an API key lacks the scope of a route
+	ErrInsufficientScope = apperr.Forbidden("api key lacks the required scope")
+	// ErrQuotaExceeded is returned when an API key used up its daily quota
+	ErrQuotaExceeded = apperr.TooManyRequests("api key quota exceeded")
+)
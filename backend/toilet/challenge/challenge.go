@@ -0,0 +1,250 @@
+// Package challenge implements a proof-of-work puzzle that clients solve
+// before registering or signing in, in place of a third-party CAPTCHA.
+//
+// A challenge is a random token signed with HMAC-SHA256, so any instance
+// sharing the secret can verify it without storing anything. The client
+// has to find a nonce such that SHA-256(token + ":" + nonce) starts with
+// Difficulty zero bits, which takes about 2^Difficulty hashes. The
+// signature covers the purpose of the challenge and the IP address it was
+// issued to, so a solution cannot be used for anything else.
+package challenge
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/bits"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Purposes a challenge can be issued for
+const (
+	PurposeLogin    = "login"
+	PurposeRegister = "register"
+)
+
+// Defaults used unless configured otherwise
+const (
+	DefaultDifficulty    = 16
+	DefaultMaxDifficulty = 22
+	DefaultTTL           = 5 * time.Minute
+	maxNonceLength       = 32
+)
+
+// Challenge is the puzzle handed to a client
+type Challenge struct {
+	Token      string    `json:"challenge"`
+	Algorithm  string    `json:"algorithm"`  // always "sha256"
+	Difficulty int       `json:"difficulty"` // leading zero bits of the hash
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// payload is the signed part of a token
+type payload struct {
+	Purpose    string `json:"p"`
+	Difficulty int    `json:"d"`
+	ExpiresAt  int64  `json:"e"`
+	Salt       string `json:"s"`
+}
+
+// Error is returned for missing, expired or wrong solutions.
+// transport.encodeError answers it with 403 and a body that tells the client to fetch a new
+// challenge.
+type Error struct {
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// StatusCode makes the transport answer with 403 Forbidden
+func (e Error) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// MarshalJSON adds challenge_required to the error body, so that clients
+// can tell it apart from other refusals
+func (e Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error             string `json:"error"`
+		ChallengeRequired bool   `json:"challenge_required"`
+	}{e.msg, true})
+}
+
+var (
+	ErrRequired = Error{"a solved challenge is required"}
+	ErrInvalid  = Error{"invalid challenge"}
+	ErrExpired  = Error{"challenge expired"}
+	ErrWrong    = Error{"wrong challenge solution"}
+	ErrReused   = Error{"challenge already used"}
+)
+
+// Issuer issues and verifies challenges
+type Issuer struct {
+	secret []byte
+	// TTL is how long a challenge can be solved and used
+	TTL time.Duration
+	// Difficulty is the difficulty of challenges while nothing suspicious
+	// is going on; MaxDifficulty caps how far it is raised
+	Difficulty    int
+	MaxDifficulty int
+
+	// Solutions seen by this instance, to refuse replays until they expire.
+	// Other instances do not know about them; the short TTL and the binding
+	// to the client IP keep what a replay can gain small.
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewIssuer creates an issuer that signs challenges with secret
+func NewIssuer(secret []byte) *Issuer {
+	return &Issuer{
+		secret:        secret,
+		TTL:           DefaultTTL,
+		Difficulty:    DefaultDifficulty,
+		MaxDifficulty: DefaultMaxDifficulty,
+		used:          map[string]time.Time{},
+	}
+}
+
+// NewIssuerFromEnv configures challenges from the environment:
+//
+//   - CHALLENGE_SECRET is the signing secret shared by all instances,
+//   - CHALLENGE_DIFFICULTY is the normal difficulty in bits; 0 turns
+//     challenges off,
+//   - CHALLENGE_MAX_DIFFICULTY caps the difficulty under abuse.
+//
+// Without a secret a random one is generated, which only works with a
+// single instance and invalidates open challenges on restart.
+func NewIssuerFromEnv() *Issuer {
+	difficulty := envInt("CHALLENGE_DIFFICULTY", DefaultDifficulty)
+	if difficulty <= 0 {
+		return nil
+	}
+
+	secret := []byte(os.Getenv("CHALLENGE_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("could not generate challenge secret: %v", err)
+		}
+		log.Println("CHALLENGE_SECRET is not set, challenges only work with a single instance")
+	}
+
+	i := NewIssuer(secret)
+	i.Difficulty = difficulty
+	i.MaxDifficulty = max(envInt("CHALLENGE_MAX_DIFFICULTY", DefaultMaxDifficulty), difficulty)
+	return i
+}
+
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+	return def
+}
+
+// Issue creates a challenge for a purpose and a client IP. The difficulty
+// is capped at MaxDifficulty.
+func (i *Issuer) Issue(purpose, clientIP string, difficulty int, now time.Time) (Challenge, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return Challenge{}, err
+	}
+	p := payload{
+		Purpose:    purpose,
+		Difficulty: min(max(difficulty, 1), i.MaxDifficulty),
+		ExpiresAt:  now.Add(i.TTL).Unix(),
+		Salt:       base64.RawURLEncoding.EncodeToString(salt),
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return Challenge{
+		Token:      body + "." + i.sign(body, clientIP),
+		Algorithm:  "sha256",
+		Difficulty: p.Difficulty,
+		ExpiresAt:  time.Unix(p.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+// Verify checks the solution of a challenge issued for the purpose and the
+// client IP. A solution is accepted once per instance.
+func (i *Issuer) Verify(token, nonce, purpose, clientIP string, now time.Time) error {
+	if token == "" || nonce == "" {
+		return ErrRequired
+	}
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || len(nonce) > maxNonceLength || !hmac.Equal([]byte(sig), []byte(i.sign(body, clientIP))) {
+		return ErrInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return ErrInvalid
+	}
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil || p.Purpose != purpose {
+		return ErrInvalid
+	}
+	expiresAt := time.Unix(p.ExpiresAt, 0)
+	if !now.Before(expiresAt) {
+		return ErrExpired
+	}
+
+	hash := sha256.Sum256([]byte(token + ":" + nonce))
+	if leadingZeroBits(hash[:]) < p.Difficulty {
+		return ErrWrong
+	}
+	if !i.markUsed(token, expiresAt, now) {
+		return ErrReused
+	}
+	return nil
+}
+
+func (i *Issuer) sign(body, clientIP string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(body + "|" + clientIP))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// markUsed remembers a solved token until it expires and reports whether
+// it was new. Expired tokens are forgotten on the way.
+func (i *Issuer) markUsed(token string, expiresAt, now time.Time) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, seen := i.used[token]; seen {
+		return false
+	}
+	for t, exp := range i.used {
+		if !now.Before(exp) {
+			delete(i.used, t)
+		}
+	}
+	i.used[token] = expiresAt
+	return true
+}
+
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c != 0 {
+			return n + bits.LeadingZeros8(c)
+		}
+		n += 8
+	}
+	return n
+}
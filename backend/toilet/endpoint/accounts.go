@@ -0,0 +1,140 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ForgotPassword Endpoint. Accepts {"login": ...} with a username or an
+// email address; "username" and "email" keys work as well.
+func makeForgotPasswordEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		login := (*req)["login"]
+		if login == "" {
+			login = (*req)["email"]
+		}
+		if login == "" {
+			login = (*req)["username"]
+		}
+
+		if err := s.ForgotPassword(login); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// ResetPassword Endpoint
+func makeResetPasswordEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.ResetPassword(ctx, (*req)["token"], (*req)["password"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// VerifyEmail Endpoint
+func makeVerifyEmailEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.VerifyEmail((*req)["token"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "verified"}, nil
+	}
+}
+
+// ResendVerification Endpoint
+func makeResendVerificationEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.ResendEmailVerification(userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "sent"}, nil
+	}
+}
+
+// ExportRequest selects the format of a personal data export: "json" (the
+// default) or "zip"
+type ExportRequest struct {
+	Format string
+}
+
+// ExportResponse is a personal data export in the requested format
+type ExportResponse struct {
+	Format string
+	Data   models.UserExport
+}
+
+// ExportMe Endpoint
+func makeExportMeEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ExportRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		if req.Format == "" {
+			req.Format = "json"
+		}
+		if req.Format != "json" && req.Format != "zip" {
+			return nil, apperr.Invalid("unsupported export format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		data, err := s.ExportUserData(userID)
+		if err != nil {
+			return nil, err
+		}
+		return ExportResponse{Format: req.Format, Data: data}, nil
+	}
+}
+
+// DeleteMe Endpoint. Requires {"password": ...} or, after signing in again
+// through a linked provider, {"reauth_token": ...} to confirm the deletion.
+func makeDeleteMeEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.DeleteAccount(userID, (*req)["password"], (*req)["reauth_token"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "account deleted"}, nil
+	}
+}
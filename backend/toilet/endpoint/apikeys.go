@@ -0,0 +1,75 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// CreateAPIKey Endpoint
+func makeCreateAPIKeyEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		key, ok := request.(*models.APIKey)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		key.UserID = userID
+
+		return s.CreateAPIKey(*key)
+	}
+}
+
+// GetMyAPIKeys Endpoint
+func makeGetMyAPIKeysEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetAPIKeys(userID)
+	}
+}
+
+// RevokeAPIKey Endpoint
+func makeRevokeAPIKeyEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.RevokeAPIKey(reqMap["id"], userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "revoked"}, nil
+	}
+}
+
+// SetAPIKeyQuota Endpoint (admins only)
+func makeSetAPIKeyQuotaEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		key, ok := request.(*models.APIKey)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.SetAPIKeyQuota(key.ID, key.DailyQuota); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
@@ -0,0 +1,91 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// PendingToiletsRequest pages through the toilets waiting for approval
+type PendingToiletsRequest struct {
+	Limit  int
+	Offset int
+}
+
+// ApprovalRequest approves or rejects a pending toilet. A reason is
+// required for rejections.
+type ApprovalRequest struct {
+	ToiletID int    `json:"id"`
+	Reason   string `json:"reason"`
+}
+
+// ConfirmToilet Endpoint. Signed-in users confirm toilets they have seen.
+func makeConfirmToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		return s.ConfirmToilet(reqMap["id"], userID)
+	}
+}
+
+// PendingToilets Endpoint (moderators only)
+func makePendingToiletsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(PendingToiletsRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.PendingToilets(req.Limit, req.Offset)
+	}
+}
+
+// ApproveToilet Endpoint (moderators only)
+func makeApproveToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ApprovalRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.ApproveToilet(req.ToiletID, userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "approved"}, nil
+	}
+}
+
+// RejectToilet Endpoint (moderators only)
+func makeRejectToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ApprovalRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.RejectToilet(req.ToiletID, userID, req.Reason); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "rejected"}, nil
+	}
+}
@@ -0,0 +1,238 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"log"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// auditSpec describes how an endpoint is recorded in the audit log
+type auditSpec struct {
+	action     string
+	targetType string
+	// target returns the ID of the target. It is called with a nil
+	// response before the endpoint runs and, if that gives no ID, with the
+	// response afterwards, which is how new objects are found. Defaults to
+	// auditID.
+	target func(ctx context.Context, request, response interface{}) string
+	// noSnapshots leaves out the snapshots, for deleted accounts whose data
+	// must not be kept
+	noSnapshots bool
+}
+
+// audited records successful calls of an endpoint in the audit log with
+// snapshots of the target before and after the call. Failing to write the
+// log does not fail the request, since the change has already been made.
+func audited(s service.Service, spec auditSpec) endpoint.Middleware {
+	if spec.target == nil {
+		spec.target = func(_ context.Context, request, response interface{}) string {
+			if response == nil {
+				return auditID(request)
+			}
+			return auditID(response)
+		}
+	}
+
+	snapshot := func(targetID string) json.RawMessage {
+		if spec.noSnapshots {
+			return nil
+		}
+		data, err := s.AuditSnapshot(spec.targetType, targetID)
+		if err != nil {
+			log.Printf("audit: could not snapshot %s %s: %v", spec.targetType, targetID, err)
+		}
+		return data
+	}
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			targetID := spec.target(ctx, request, nil)
+			before := snapshot(targetID)
+
+			response, err := next(ctx, request)
+			if err != nil {
+				return response, err
+			}
+
+			if targetID == "" {
+				targetID = spec.target(ctx, request, response)
+			}
+			entry := models.AuditEntry{
+				Action:     spec.action,
+				TargetType: spec.targetType,
+				TargetID:   targetID,
+				Before:     before,
+				After:      snapshot(targetID),
+				RequestID:  auth.GetRequestID(ctx),
+				IP:         auth.GetClientIP(ctx),
+				UserAgent:  auth.GetUserAgent(ctx),
+			}
+			if userID, ok := auth.GetUserID(ctx); ok {
+				entry.ActorID = &userID
+			}
+			if keyID, ok := auth.GetAPIKeyID(ctx); ok {
+				entry.APIKeyID = &keyID
+			}
+			if err := s.RecordAudit(entry); err != nil {
+				log.Printf("audit: could not record %s of %s %s: %v", spec.action, spec.targetType, targetID, err)
+			}
+			return response, nil
+		}
+	}
+}
+
+// auditID finds the ID of the target in a request or response. It returns
+// "" for values without one, including new objects that have no ID yet.
+func auditID(v interface{}) string {
+	var id int
+	switch v := v.(type) {
+	case map[string]int:
+		id = v["id"]
+	case map[string]interface{}:
+		id, _ = v["id"].(int)
+	case *models.Toilet:
+		id = v.ID
+	case *models.Review:
+		id = v.ID
+	case *models.Comment:
+		id = v.ID
+	case models.Comment:
+		id = v.ID
+	case *models.ToiletList:
+		id = v.ID
+	case models.ToiletList:
+		id = v.ID
+	case *models.ListItem:
+		id = v.ListID
+	case *models.APIKey:
+		id = v.ID
+	case models.APIKey:
+		id = v.ID
+	case models.Report:
+		id = v.ID
+	case *models.Resolution:
+		id = v.ItemID
+	case *AssignRequest:
+		id = v.ItemID
+	case *NoteRequest:
+		id = v.ItemID
+	case *SetRoleRequest:
+		id = v.UserID
+	case *ApprovalRequest:
+		id = v.ToiletID
+	case models.Ban:
+		id = v.ID
+	case models.Appeal:
+		id = v.ID
+	case *DecideAppealRequest:
+		id = v.AppealID
+	case string:
+		return v
+	}
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// auditSelf is the target of operations of users on their own account
+func auditSelf(ctx context.Context, _, _ interface{}) string {
+	if userID, ok := auth.GetUserID(ctx); ok {
+		return strconv.Itoa(userID)
+	}
+	return ""
+}
+
+// auditLockout is the target of unlocking a login: the username or IP
+func auditLockout(_ context.Context, request, _ interface{}) string {
+	if req, ok := request.(*map[string]string); ok {
+		if username := (*req)["username"]; username != "" {
+			return username
+		}
+		return (*req)["ip"]
+	}
+	return ""
+}
+
+// auditEndpoints wraps every endpoint that changes state with audited.
+// Endpoints that only read and signing in and out are not audited. Nor are
+// the email links and the callback of providers, which identify the user
+// by a token only; the service records the accounts they create or change.
+func auditEndpoints(s service.Service, e Endpoints) Endpoints {
+	audit := func(ep *endpoint.Endpoint, spec auditSpec) {
+		*ep = audited(s, spec)(*ep)
+	}
+
+	audit(&e.CreateUser, auditSpec{action: "user.create", targetType: models.TargetUser})
+	audit(&e.UpdateMe, auditSpec{action: "user.update", targetType: models.TargetUser, target: auditSelf})
+	audit(&e.DeleteMe, auditSpec{action: "user.delete", targetType: models.TargetUser, target: auditSelf, noSnapshots: true})
+	audit(&e.SetUserRole, auditSpec{action: "user.role", targetType: models.TargetUser})
+	audit(&e.UnlinkIdentity, auditSpec{action: "user.identity_unlink", targetType: models.TargetUser, target: auditSelf})
+	audit(&e.ConfirmTOTP, auditSpec{action: "user.2fa_enable", targetType: models.TargetUser, target: auditSelf})
+	audit(&e.DisableTOTP, auditSpec{action: "user.2fa_disable", targetType: models.TargetUser, target: auditSelf})
+	audit(&e.RegenRecoveryCodes, auditSpec{action: "user.2fa_recovery_codes", targetType: models.TargetUser, target: auditSelf})
+	audit(&e.RevokeSession, auditSpec{action: "session.revoke", targetType: models.TargetSession})
+	audit(&e.UnlockLogin, auditSpec{action: "login.unlock", targetType: models.TargetLogin, target: auditLockout})
+
+	audit(&e.AddToilet, auditSpec{action: "toilet.add", targetType: models.TargetToilet})
+	audit(&e.UpdateToilet, auditSpec{action: "toilet.update", targetType: models.TargetToilet})
+	audit(&e.DeleteToilet, auditSpec{action: "toilet.delete", targetType: models.TargetToilet})
+	audit(&e.HideToilet, auditSpec{action: "toilet.hide", targetType: models.TargetToilet})
+	audit(&e.RestoreToilet, auditSpec{action: "toilet.restore", targetType: models.TargetToilet})
+	audit(&e.ConfirmToilet, auditSpec{action: "toilet.confirm", targetType: models.TargetToilet})
+	audit(&e.ApproveToilet, auditSpec{action: "toilet.approve", targetType: models.TargetToilet})
+	audit(&e.RejectToilet, auditSpec{action: "toilet.reject", targetType: models.TargetToilet})
+
+	audit(&e.AddReview, auditSpec{action: "review.add", targetType: models.TargetReview})
+	audit(&e.UpdateReview, auditSpec{action: "review.update", targetType: models.TargetReview})
+	audit(&e.DeleteReview, auditSpec{action: "review.delete", targetType: models.TargetReview})
+	audit(&e.HideReview, auditSpec{action: "review.hide", targetType: models.TargetReview})
+	audit(&e.RestoreReview, auditSpec{action: "review.restore", targetType: models.TargetReview})
+
+	audit(&e.AddComment, auditSpec{action: "comment.add", targetType: models.TargetComment})
+	audit(&e.UpdateComment, auditSpec{action: "comment.update", targetType: models.TargetComment})
+	audit(&e.DeleteComment, auditSpec{action: "comment.delete", targetType: models.TargetComment})
+	audit(&e.HideComment, auditSpec{action: "comment.hide", targetType: models.TargetComment})
+	audit(&e.RestoreComment, auditSpec{action: "comment.restore", targetType: models.TargetComment})
+
+	audit(&e.CreateList, auditSpec{action: "list.create", targetType: models.TargetList})
+	audit(&e.UpdateList, auditSpec{action: "list.update", targetType: models.TargetList})
+	audit(&e.DeleteList, auditSpec{action: "list.delete", targetType: models.TargetList})
+	audit(&e.AddListItem, auditSpec{action: "list.item_add", targetType: models.TargetList})
+	audit(&e.UpdateListItem, auditSpec{action: "list.item_update", targetType: models.TargetList})
+	audit(&e.RemoveListItem, auditSpec{action: "list.item_remove", targetType: models.TargetList})
+
+	audit(&e.CreateAPIKey, auditSpec{action: "apikey.create", targetType: models.TargetAPIKey})
+	audit(&e.RevokeAPIKey, auditSpec{action: "apikey.revoke", targetType: models.TargetAPIKey})
+	audit(&e.SetAPIKeyQuota, auditSpec{action: "apikey.quota", targetType: models.TargetAPIKey})
+
+	audit(&e.Report, auditSpec{action: "report.add", targetType: models.TargetReport})
+	audit(&e.AssignItem, auditSpec{action: "moderation.assign", targetType: models.TargetModerationItem})
+	audit(&e.AddItemNote, auditSpec{action: "moderation.note", targetType: models.TargetModerationItem})
+	audit(&e.ResolveItem, auditSpec{action: "moderation.resolve", targetType: models.TargetModerationItem})
+	audit(&e.BanUser, auditSpec{action: "ban.add", targetType: models.TargetBan})
+	audit(&e.LiftBan, auditSpec{action: "ban.lift", targetType: models.TargetBan})
+	audit(&e.AppealBan, auditSpec{action: "appeal.add", targetType: models.TargetAppeal})
+	audit(&e.DecideAppeal, auditSpec{action: "appeal.decide", targetType: models.TargetAppeal})
+	audit(&e.VerifyOwner, auditSpec{action: "owner.verify", targetType: models.TargetToiletOwner, target: auditOwner})
+	audit(&e.RevokeOwner, auditSpec{action: "owner.revoke", targetType: models.TargetToiletOwner, target: auditOwner})
+	return e
+}
+
+// AuditLog Endpoint (admins only)
+func makeAuditLogEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		filter, ok := request.(models.AuditFilter)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.QueryAuditLog(filter)
+	}
+}
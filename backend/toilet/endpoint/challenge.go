@@ -0,0 +1,23 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// NewChallenge Endpoint. Hands out a proof-of-work challenge for the
+// purpose given in the request; its solution is sent along with the
+// registration or login.
+func makeNewChallengeEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		purpose, ok := request.(string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.NewChallenge(purpose, auth.GetClientIP(ctx))
+	}
+}
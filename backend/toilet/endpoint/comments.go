@@ -0,0 +1,92 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// AddComment Endpoint
+func makeAddCommentEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		comment, ok := request.(*models.Comment)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		comment.UserID = userID
+
+		return s.AddComment(*comment)
+	}
+}
+
+// UpdateComment Endpoint
+func makeUpdateCommentEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		comment, ok := request.(*models.Comment)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		comment.UserID = userID
+
+		return s.UpdateComment(*comment)
+	}
+}
+
+// DeleteComment Endpoint. Moderators may delete any comment, everyone else
+// only their own.
+func makeDeleteCommentEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		var err error
+		if auth.Can(ctx, auth.PermModerateContent) {
+			err = s.DeleteCommentAsModerator(reqMap["id"])
+		} else {
+			err = s.DeleteComment(reqMap["id"], userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "deleted"}, nil
+	}
+}
+
+// GetCommentsByReview Endpoint
+func makeGetCommentsByReviewEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reviewID, ok := request.(string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		reviewIDInt, err := strconv.Atoi(reviewID)
+		if err != nil {
+			return nil, apperr.Invalid("invalid review ID")
+		}
+		viewerID, _ := auth.GetUserID(ctx)
+		return s.GetCommentsByReview(reviewIDInt, viewerID)
+	}
+}
@@ -2,17 +2,15 @@ package endpoint
 
 import (
 	"context"
-	"errors"
+	"free_toilet_map/toilet/apperr"
 	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/challenge"
 	models "free_toilet_map/toilet/model"
 	"free_toilet_map/toilet/service"
 	"log"
 	"strconv"
-	"time"
 
 	"github.com/go-kit/kit/endpoint"
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Endpoints struct {
@@ -23,10 +21,90 @@ type Endpoints struct {
 	Login              endpoint.Endpoint
 	GetReviewsByToilet endpoint.Endpoint
 	DeleteToilet       endpoint.Endpoint
+	CreateList         endpoint.Endpoint
+	UpdateList         endpoint.Endpoint
+	DeleteList         endpoint.Endpoint
+	GetMyLists         endpoint.Endpoint
+	GetSharedList      endpoint.Endpoint
+	AddListItem        endpoint.Endpoint
+	UpdateListItem     endpoint.Endpoint
+	RemoveListItem     endpoint.Endpoint
+	GetMe              endpoint.Endpoint
+	UpdateMe           endpoint.Endpoint
+	ExportMe           endpoint.Endpoint
+	DeleteMe           endpoint.Endpoint
+	GetMyToilets       endpoint.Endpoint
+	GetMyReviews       endpoint.Endpoint
+	GetUserProfile     endpoint.Endpoint
+	AddComment         endpoint.Endpoint
+	UpdateComment      endpoint.Endpoint
+	DeleteComment      endpoint.Endpoint
+	GetComments        endpoint.Endpoint
+	RefreshToken       endpoint.Endpoint
+	Logout             endpoint.Endpoint
+	LogoutAll          endpoint.Endpoint
+	JWKS               endpoint.Endpoint
+	UpdateToilet       endpoint.Endpoint
+	UpdateReview       endpoint.Endpoint
+	DeleteReview       endpoint.Endpoint
+	HideComment        endpoint.Endpoint
+	RestoreComment     endpoint.Endpoint
+	SetUserRole        endpoint.Endpoint
+	ForgotPassword     endpoint.Endpoint
+	ResetPassword      endpoint.Endpoint
+	VerifyEmail        endpoint.Endpoint
+	ResendVerification endpoint.Endpoint
+	ExternalProviders  endpoint.Endpoint
+	ExternalLogin      endpoint.Endpoint
+	ExternalCallback   endpoint.Endpoint
+	LinkExternal       endpoint.Endpoint
+	ReauthExternal     endpoint.Endpoint
+	GetMyIdentities    endpoint.Endpoint
+	UnlinkIdentity     endpoint.Endpoint
+	CreateAPIKey       endpoint.Endpoint
+	GetMyAPIKeys       endpoint.Endpoint
+	RevokeAPIKey       endpoint.Endpoint
+	SetAPIKeyQuota     endpoint.Endpoint
+	UnlockLogin        endpoint.Endpoint
+	CompleteLogin      endpoint.Endpoint
+	GetTOTPStatus      endpoint.Endpoint
+	EnrollTOTP         endpoint.Endpoint
+	ConfirmTOTP        endpoint.Endpoint
+	DisableTOTP        endpoint.Endpoint
+	RegenRecoveryCodes endpoint.Endpoint
+	GetMySessions      endpoint.Endpoint
+	RevokeSession      endpoint.Endpoint
+	Report             endpoint.Endpoint
+	ModerationQueue    endpoint.Endpoint
+	ModerationItem     endpoint.Endpoint
+	AssignItem         endpoint.Endpoint
+	AddItemNote        endpoint.Endpoint
+	ResolveItem        endpoint.Endpoint
+	HideToilet         endpoint.Endpoint
+	RestoreToilet      endpoint.Endpoint
+	HideReview         endpoint.Endpoint
+	RestoreReview      endpoint.Endpoint
+	GetMyReputation    endpoint.Endpoint
+	AuditLog           endpoint.Endpoint
+	ConfirmToilet      endpoint.Endpoint
+	PendingToilets     endpoint.Endpoint
+	ApproveToilet      endpoint.Endpoint
+	RejectToilet       endpoint.Endpoint
+	NewChallenge       endpoint.Endpoint
+	BanUser            endpoint.Endpoint
+	LiftBan            endpoint.Endpoint
+	UserBans           endpoint.Endpoint
+	AppealBan          endpoint.Endpoint
+	Appeals            endpoint.Endpoint
+	DecideAppeal       endpoint.Endpoint
+	VerifyOwner        endpoint.Endpoint
+	RevokeOwner        endpoint.Endpoint
 }
 
+// MakeEndpoints creates the endpoints of the service. Endpoints that change
+// state are recorded in the audit log.
 func MakeEndpoints(svc service.Service) Endpoints {
-	return Endpoints{
+	e := Endpoints{
 		CreateUser:         makeCreateUserEndpoint(svc),
 		ListToilets:        makeListToiletsEndpoint(svc),
 		AddReview:          makeAddReviewEndpoint(svc),
@@ -34,43 +112,103 @@ func MakeEndpoints(svc service.Service) Endpoints {
 		Login:              makeLoginEndpoint(svc),
 		GetReviewsByToilet: makeGetReviewsByToiletEndpoint(svc),
 		DeleteToilet:       makeDeleteToiletEndpoint(svc),
+		CreateList:         makeCreateListEndpoint(svc),
+		UpdateList:         makeUpdateListEndpoint(svc),
+		DeleteList:         makeDeleteListEndpoint(svc),
+		GetMyLists:         makeGetMyListsEndpoint(svc),
+		GetSharedList:      makeGetSharedListEndpoint(svc),
+		AddListItem:        makeAddListItemEndpoint(svc),
+		UpdateListItem:     makeUpdateListItemEndpoint(svc),
+		RemoveListItem:     makeRemoveListItemEndpoint(svc),
+		GetMe:              makeGetMeEndpoint(svc),
+		UpdateMe:           makeUpdateMeEndpoint(svc),
+		ExportMe:           makeExportMeEndpoint(svc),
+		DeleteMe:           makeDeleteMeEndpoint(svc),
+		GetMyToilets:       makeGetMyToiletsEndpoint(svc),
+		GetMyReviews:       makeGetMyReviewsEndpoint(svc),
+		GetUserProfile:     makeGetUserProfileEndpoint(svc),
+		AddComment:         makeAddCommentEndpoint(svc),
+		UpdateComment:      makeUpdateCommentEndpoint(svc),
+		DeleteComment:      makeDeleteCommentEndpoint(svc),
+		GetComments:        makeGetCommentsByReviewEndpoint(svc),
+		RefreshToken:       makeRefreshTokenEndpoint(svc),
+		Logout:             makeLogoutEndpoint(svc),
+		LogoutAll:          makeLogoutAllEndpoint(svc),
+		JWKS:               makeJWKSEndpoint(svc),
+		UpdateToilet:       makeUpdateToiletEndpoint(svc),
+		UpdateReview:       makeUpdateReviewEndpoint(svc),
+		DeleteReview:       makeDeleteReviewEndpoint(svc),
+		HideComment:        RequirePermission(auth.PermModerateContent)(makeHideCommentEndpoint(svc)),
+		RestoreComment:     RequirePermission(auth.PermModerateContent)(makeRestoreCommentEndpoint(svc)),
+		SetUserRole:        RequirePermission(auth.PermManageRoles)(makeSetUserRoleEndpoint(svc)),
+		ForgotPassword:     makeForgotPasswordEndpoint(svc),
+		ResetPassword:      makeResetPasswordEndpoint(svc),
+		VerifyEmail:        makeVerifyEmailEndpoint(svc),
+		ResendVerification: makeResendVerificationEndpoint(svc),
+		ExternalProviders:  makeExternalProvidersEndpoint(svc),
+		ExternalLogin:      makeExternalLoginEndpoint(svc),
+		ExternalCallback:   makeExternalLoginCallbackEndpoint(svc),
+		LinkExternal:       makeLinkExternalAccountEndpoint(svc),
+		ReauthExternal:     makeReauthExternalEndpoint(svc),
+		GetMyIdentities:    makeGetMyIdentitiesEndpoint(svc),
+		UnlinkIdentity:     makeUnlinkIdentityEndpoint(svc),
+		CreateAPIKey:       makeCreateAPIKeyEndpoint(svc),
+		GetMyAPIKeys:       makeGetMyAPIKeysEndpoint(svc),
+		RevokeAPIKey:       makeRevokeAPIKeyEndpoint(svc),
+		SetAPIKeyQuota:     RequirePermission(auth.PermManageAPIKeys)(makeSetAPIKeyQuotaEndpoint(svc)),
+		UnlockLogin:        RequirePermission(auth.PermManageAccounts)(makeUnlockLoginEndpoint(svc)),
+		CompleteLogin:      makeCompleteLoginEndpoint(svc),
+		GetTOTPStatus:      makeGetTOTPStatusEndpoint(svc),
+		EnrollTOTP:         makeEnrollTOTPEndpoint(svc),
+		ConfirmTOTP:        makeConfirmTOTPEndpoint(svc),
+		DisableTOTP:        makeDisableTOTPEndpoint(svc),
+		RegenRecoveryCodes: makeRegenerateRecoveryCodesEndpoint(svc),
+		GetMySessions:      makeGetMySessionsEndpoint(svc),
+		RevokeSession:      makeRevokeSessionEndpoint(svc),
+		Report:             makeReportEndpoint(svc),
+		ModerationQueue:    RequirePermission(auth.PermModerateContent)(makeModerationQueueEndpoint(svc)),
+		ModerationItem:     RequirePermission(auth.PermModerateContent)(makeGetModerationItemEndpoint(svc)),
+		AssignItem:         RequirePermission(auth.PermModerateContent)(makeAssignModerationItemEndpoint(svc)),
+		AddItemNote:        RequirePermission(auth.PermModerateContent)(makeAddModerationNoteEndpoint(svc)),
+		ResolveItem:        RequirePermission(auth.PermModerateContent)(makeResolveModerationItemEndpoint(svc)),
+		HideToilet:         RequirePermission(auth.PermModerateContent)(makeHideToiletEndpoint(svc)),
+		RestoreToilet:      RequirePermission(auth.PermModerateContent)(makeRestoreToiletEndpoint(svc)),
+		HideReview:         RequirePermission(auth.PermModerateContent)(makeHideReviewEndpoint(svc)),
+		RestoreReview:      RequirePermission(auth.PermModerateContent)(makeRestoreReviewEndpoint(svc)),
+		GetMyReputation:    makeGetMyReputationEndpoint(svc),
+		AuditLog:           RequirePermission(auth.PermViewAuditLog)(makeAuditLogEndpoint(svc)),
+		ConfirmToilet:      makeConfirmToiletEndpoint(svc),
+		PendingToilets:     RequirePermission(auth.PermModerateContent)(makePendingToiletsEndpoint(svc)),
+		ApproveToilet:      RequirePermission(auth.PermModerateContent)(makeApproveToiletEndpoint(svc)),
+		RejectToilet:       RequirePermission(auth.PermModerateContent)(makeRejectToiletEndpoint(svc)),
+		NewChallenge:       makeNewChallengeEndpoint(svc),
+		BanUser:            RequirePermission(auth.PermModerateContent)(makeBanUserEndpoint(svc)),
+		LiftBan:            RequirePermission(auth.PermModerateContent)(makeLiftBanEndpoint(svc)),
+		UserBans:           RequirePermission(auth.PermModerateContent)(makeUserBansEndpoint(svc)),
+		AppealBan:          makeAppealBanEndpoint(svc),
+		Appeals:            RequirePermission(auth.PermModerateContent)(makeAppealsEndpoint(svc)),
+		DecideAppeal:       RequirePermission(auth.PermModerateContent)(makeDecideAppealEndpoint(svc)),
+		VerifyOwner:        RequirePermission(auth.PermModerateContent)(makeVerifyOwnerEndpoint(svc)),
+		RevokeOwner:        RequirePermission(auth.PermModerateContent)(makeRevokeOwnerEndpoint(svc)),
 	}
+	return auditEndpoints(svc, e)
 }
 
 // Login Endpoint
-func makeLoginEndpoint(svc service.Service) endpoint.Endpoint {
+func makeLoginEndpoint(s service.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(*map[string]string)
 		if !ok {
-			return nil, errors.New("invalid request format")
+			return nil, apperr.Invalid("invalid request format")
 		}
 
 		username := (*req)["username"]
 		password := (*req)["password"]
 
-		// Validate user credentials
-		user, err := svc.GetUserByUsername(username)
-		if err != nil {
+		if err := s.VerifyChallenge(challenge.PurposeLogin, auth.GetClientIP(ctx), (*req)["challenge"], (*req)["nonce"]); err != nil {
 			return nil, err
 		}
-
-		// Validate password using bcrypt
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-			return nil, errors.New("invalid credentials")
-		}
-
-		// Generate JWT
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"user_id": user.ID,
-			"exp":     time.Now().Add(72 * time.Hour).Unix(),
-		})
-
-		tokenString, err := token.SignedString([]byte("secret_key"))
-		if err != nil {
-			return nil, err
-		}
-
-		return map[string]string{"token": tokenString}, nil
+		return s.Login(username, password, clientInfo(ctx))
 	}
 }
 
@@ -79,12 +217,12 @@ func makeAddToiletEndpoint(s service.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		toiletPtr, ok := request.(*models.Toilet)
 		if !ok {
-			return nil, errors.New("invalid request format")
+			return nil, apperr.Invalid("invalid request format")
 		}
 
 		userID, ok := auth.GetUserID(ctx)
 		if !ok {
-			return nil, errors.New("unauthorized")
+			return nil, apperr.Unauthorized("unauthorized")
 		}
 
 		toilet := *toiletPtr
@@ -103,6 +241,8 @@ func makeAddToiletEndpoint(s service.Service) endpoint.Endpoint {
 			"gender":     savedToilet.Gender,
 			"type":       savedToilet.Type,
 			"address":    savedToilet.Address,
+			"status":     savedToilet.Status,
+			"approval":   savedToilet.Approval,
 		}, nil
 	}
 }
@@ -112,7 +252,7 @@ func makeDeleteToiletEndpoint(s service.Service) endpoint.Endpoint {
 		reqMap, ok := request.(map[string]int) // Мы ожидаем, что запрос будет map[string]int
 		if !ok {
 			log.Printf("Failed to cast request to map[string]int. Got: %T\n", request)
-			return nil, errors.New("invalid request format")
+			return nil, apperr.Invalid("invalid request format")
 		}
 
 		toiletID := reqMap["id"] // Получаем id как целое число
@@ -121,11 +261,16 @@ func makeDeleteToiletEndpoint(s service.Service) endpoint.Endpoint {
 		userID, ok := auth.GetUserID(ctx)
 		if !ok {
 			log.Println("User not authenticated, unable to get user ID.")
-			return nil, errors.New("unauthorized")
+			return nil, apperr.Unauthorized("unauthorized")
 		}
 
-		// Пытаемся удалить туалет
-		err := s.DeleteToilet(toiletID, userID)
+		// Пытаемся удалить туалет; модераторы могут удалить любой
+		var err error
+		if auth.Can(ctx, auth.PermModerateContent) {
+			err = s.DeleteToiletAsModerator(toiletID)
+		} else {
+			err = s.DeleteToilet(toiletID, userID)
+		}
 		if err != nil {
 			log.Printf("Error deleting toilet with ID %d: %v", toiletID, err)
 			return nil, err
@@ -142,26 +287,25 @@ func makeCreateUserEndpoint(s service.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(*map[string]string)
 		if !ok {
-			return nil, errors.New("invalid request format")
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.VerifyChallenge(challenge.PurposeRegister, auth.GetClientIP(ctx), (*req)["challenge"], (*req)["nonce"]); err != nil {
+			return nil, err
 		}
 
 		// Получаем данные пользователя из запроса
 		username := (*req)["username"]
 		password := (*req)["password"]
+		email := (*req)["email"]
 
-		// Хэшируем пароль перед сохранением
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, errors.New("failed to hash password")
-		}
-
-		// Создаем структуру пользователя с хэшированным паролем
+		// Сервис проверяет имя и пароль и хэширует пароль перед сохранением
 		user := models.User{
 			Username: username,
-			Password: string(hashedPassword),
+			Password: password,
+			Email:    email,
 		}
 
-		// Передаем структуру пользователя в сервис для сохранения в базе данных
 		createdUser, err := s.CreateUser(user)
 		if err != nil {
 			return nil, err
@@ -171,15 +315,18 @@ func makeCreateUserEndpoint(s service.Service) endpoint.Endpoint {
 		return map[string]interface{}{
 			"id":            createdUser.ID,
 			"username":      createdUser.Username,
+			"email":         createdUser.Email,
 			"toilets_found": createdUser.ToiletsFound,
 		}, nil
 	}
 }
 
-// ListToilets Endpoint
+// ListToilets Endpoint. Signed-in users are passed on so that they see
+// their own toilets while shadow-banned.
 func makeListToiletsEndpoint(s service.Service) endpoint.Endpoint {
 	return func(ctx context.Context, _ interface{}) (interface{}, error) {
-		return s.ListToilets()
+		viewerID, _ := auth.GetUserID(ctx)
+		return s.ListToilets(viewerID)
 	}
 }
 
@@ -190,17 +337,21 @@ func makeAddReviewEndpoint(s service.Service) endpoint.Endpoint {
 
 		userID, ok := auth.GetUserID(ctx)
 		if !ok {
-			return nil, errors.New("unauthorized")
+			return nil, apperr.Unauthorized("unauthorized")
 		}
 
 		reviewPtr.UserID = userID
 
-		err := s.AddReview(*reviewPtr)
+		saved, err := s.AddReview(*reviewPtr)
 		if err != nil {
 			return nil, err
 		}
 
-		return map[string]string{"status": "ok"}, nil
+		// Held reviews are published once a moderator approves them
+		if saved.Status == models.StatusPending {
+			return map[string]interface{}{"status": models.StatusPending, "id": saved.ID}, nil
+		}
+		return map[string]interface{}{"status": "ok", "id": saved.ID}, nil
 	}
 }
 
@@ -209,16 +360,17 @@ func makeGetReviewsByToiletEndpoint(s service.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		toiletID, ok := request.(string)
 		if !ok {
-			return nil, errors.New("invalid request format")
+			return nil, apperr.Invalid("invalid request format")
 		}
 
 		// Convert string to int
 		toiletIDInt, err := strconv.Atoi(toiletID)
 		if err != nil {
-			return nil, errors.New("invalid toilet ID")
+			return nil, apperr.Invalid("invalid toilet ID")
 		}
 
-		reviews, err := s.GetReviewsByToilet(toiletIDInt)
+		viewerID, _ := auth.GetUserID(ctx)
+		reviews, err := s.GetReviewsByToilet(toiletIDInt, viewerID)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,165 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// SharedListRequest identifies a shared list and the format it should be returned in
+type SharedListRequest struct {
+	Slug   string
+	Format string // "json" (default) or "geojson"
+}
+
+// CreateList Endpoint
+func makeCreateListEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		list, ok := request.(*models.ToiletList)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		list.UserID = userID
+
+		return s.CreateList(*list)
+	}
+}
+
+// UpdateList Endpoint
+func makeUpdateListEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		list, ok := request.(*models.ToiletList)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		list.UserID = userID
+
+		if err := s.UpdateList(*list); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// DeleteList Endpoint
+func makeDeleteListEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.DeleteList(reqMap["id"], userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "deleted"}, nil
+	}
+}
+
+// GetMyLists Endpoint
+func makeGetMyListsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetUserLists(userID)
+	}
+}
+
+// GetSharedList Endpoint
+func makeGetSharedListEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(SharedListRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		viewerID, _ := auth.GetUserID(ctx)
+		list, err := s.GetSharedList(req.Slug, viewerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Format == "geojson" {
+			return models.ListFeatureCollection(list), nil
+		}
+		return list, nil
+	}
+}
+
+// AddListItem Endpoint
+func makeAddListItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		item, ok := request.(*models.ListItem)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		return s.AddListItem(*item, userID)
+	}
+}
+
+// UpdateListItem Endpoint
+func makeUpdateListItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		item, ok := request.(*models.ListItem)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.UpdateListItem(*item, userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// RemoveListItem Endpoint
+func makeRemoveListItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		item, ok := request.(*models.ListItem)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.RemoveListItem(item.ListID, item.ToiletID, userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "deleted"}, nil
+	}
+}
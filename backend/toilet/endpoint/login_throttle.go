@@ -0,0 +1,25 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// UnlockLogin Endpoint. Accepts {"username": ..., "ip": ...}; either may be
+// left out.
+func makeUnlockLoginEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.UnlockLogin((*req)["username"], (*req)["ip"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "unlocked"}, nil
+	}
+}
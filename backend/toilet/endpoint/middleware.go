@@ -0,0 +1,24 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// RequirePermission rejects requests of users whose role lacks the permission
+func RequirePermission(p auth.Permission) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if _, ok := auth.GetUserID(ctx); !ok {
+				return nil, apperr.Unauthorized("unauthorized")
+			}
+			if !auth.Can(ctx, p) {
+				return nil, apperr.Forbidden("forbidden")
+			}
+			return next(ctx, request)
+		}
+	}
+}
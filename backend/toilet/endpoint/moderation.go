@@ -0,0 +1,148 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// SetRoleRequest changes the role of a user
+type SetRoleRequest struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// UpdateToilet Endpoint. Moderators may update any toilet, everyone else
+// only the toilets they added.
+func makeUpdateToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		toilet, ok := request.(*models.Toilet)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		var err error
+		if auth.Can(ctx, auth.PermModerateContent) {
+			err = s.UpdateToiletAsModerator(*toilet)
+		} else {
+			err = s.UpdateToilet(*toilet, userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// UpdateReview Endpoint. Moderators may update any review, everyone else
+// only their own.
+func makeUpdateReviewEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		review, ok := request.(*models.Review)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		var err error
+		if auth.Can(ctx, auth.PermModerateContent) {
+			err = s.UpdateReviewAsModerator(*review)
+		} else {
+			err = s.UpdateReview(*review, userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// DeleteReview Endpoint. Moderators may delete any review, everyone else
+// only their own.
+func makeDeleteReviewEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		var err error
+		if auth.Can(ctx, auth.PermModerateContent) {
+			err = s.DeleteReviewAsModerator(reqMap["id"])
+		} else {
+			err = s.DeleteReview(reqMap["id"], userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "deleted"}, nil
+	}
+}
+
+// HideComment Endpoint (moderators only)
+func makeHideCommentEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.HideComment(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "hidden"}, nil
+	}
+}
+
+// RestoreComment Endpoint (moderators only)
+func makeRestoreCommentEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.RestoreComment(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "visible"}, nil
+	}
+}
+
+// SetUserRole Endpoint (admins only)
+func makeSetUserRoleEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*SetRoleRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		actorID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.SetUserRole(actorID, req.UserID, auth.Role(req.Role)); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
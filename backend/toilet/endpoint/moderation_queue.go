@@ -0,0 +1,228 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ModerationQueueRequest filters the moderation queue. Assignee is a user
+// ID, "me" or "none".
+type ModerationQueueRequest struct {
+	State      string
+	TargetType string
+	Reason     string
+	Assignee   string
+	Limit      int
+	Offset     int
+}
+
+// AssignRequest assigns a queue item. Without an assignee the item is
+// assigned to the moderator making the request; 0 releases it.
+type AssignRequest struct {
+	ItemID     int  `json:"id"`
+	AssigneeID *int `json:"assignee_id"`
+}
+
+// NoteRequest adds a note to a queue item
+type NoteRequest struct {
+	ItemID int    `json:"id"`
+	Text   string `json:"text"`
+}
+
+// Report Endpoint. Any signed-in user may report content.
+func makeReportEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		report, ok := request.(*models.Report)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		report.ReporterID = &userID
+
+		return s.ReportContent(*report)
+	}
+}
+
+// ModerationQueue Endpoint (moderators only)
+func makeModerationQueueEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ModerationQueueRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		filter := models.ModerationFilter{
+			State:      req.State,
+			TargetType: req.TargetType,
+			Reason:     req.Reason,
+			Limit:      req.Limit,
+			Offset:     req.Offset,
+		}
+		switch req.Assignee {
+		case "":
+		case "none":
+			filter.Unassigned = true
+		case "me":
+			userID, ok := auth.GetUserID(ctx)
+			if !ok {
+				return nil, apperr.Unauthorized("unauthorized")
+			}
+			filter.AssigneeID = userID
+		default:
+			assigneeID, err := strconv.Atoi(req.Assignee)
+			if err != nil {
+				return nil, apperr.Invalid("invalid assignee")
+			}
+			filter.AssigneeID = assigneeID
+		}
+
+		return s.ModerationQueue(filter)
+	}
+}
+
+// GetModerationItem Endpoint (moderators only)
+func makeGetModerationItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		itemID, ok := request.(string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		itemIDInt, err := strconv.Atoi(itemID)
+		if err != nil {
+			return nil, apperr.Invalid("invalid item ID")
+		}
+		return s.GetModerationItem(itemIDInt)
+	}
+}
+
+// AssignModerationItem Endpoint (moderators only)
+func makeAssignModerationItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AssignRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		assigneeID := userID
+		if req.AssigneeID != nil {
+			assigneeID = *req.AssigneeID
+		}
+		if err := s.AssignModerationItem(req.ItemID, assigneeID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok"}, nil
+	}
+}
+
+// AddModerationNote Endpoint (moderators only)
+func makeAddModerationNoteEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*NoteRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		return s.AddModerationNote(req.ItemID, userID, req.Text)
+	}
+}
+
+// ResolveModerationItem Endpoint (moderators only)
+func makeResolveModerationItemEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		res, ok := request.(*models.Resolution)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.ResolveModerationItem(userID, *res); err != nil {
+			return nil, err
+		}
+		return s.GetModerationItem(res.ItemID)
+	}
+}
+
+// HideToilet Endpoint (moderators only)
+func makeHideToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.HideToilet(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "hidden"}, nil
+	}
+}
+
+// RestoreToilet Endpoint (moderators only)
+func makeRestoreToiletEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.RestoreToilet(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "visible"}, nil
+	}
+}
+
+// HideReview Endpoint (moderators only)
+func makeHideReviewEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.HideReview(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "hidden"}, nil
+	}
+}
+
+// RestoreReview Endpoint (moderators only)
+func makeRestoreReviewEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		if err := s.RestoreReview(reqMap["id"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "visible"}, nil
+	}
+}
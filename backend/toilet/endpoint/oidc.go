@@ -0,0 +1,217 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ExternalLoginRequest carries the provider name and, on the callback, the
+// parameters the provider redirected back with and the state kept by the
+// browser. CookieMode is set when the browser asked for a cookie session.
+type ExternalLoginRequest struct {
+	Provider     string
+	Code         string
+	State        string
+	Error        string
+	BrowserState string
+	CookieMode   bool
+}
+
+// Redirect tells the transport to answer with a 302 to URL. A non-empty
+// State is kept in the browser for the callback of the provider, along
+// with CookieMode. A Session is set as cookies instead of being put into
+// the URL.
+type Redirect struct {
+	URL        string            `json:"url"`
+	State      string            `json:"-"`
+	CookieMode bool              `json:"-"`
+	Session    *models.TokenPair `json:"-"`
+}
+
+// ExternalProviders Endpoint
+func makeExternalProvidersEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return map[string][]string{"providers": s.ExternalProviders()}, nil
+	}
+}
+
+// ExternalLogin Endpoint, redirects the browser to the provider
+func makeExternalLoginEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ExternalLoginRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		authURL, state, err := s.StartExternalLogin(ctx, req.Provider, 0)
+		if err != nil {
+			return nil, err
+		}
+		return Redirect{URL: authURL, State: state, CookieMode: req.CookieMode}, nil
+	}
+}
+
+// LinkExternalAccount Endpoint. Returns the provider URL as JSON, since the
+// request is made by the frontend with the access token; the state is set
+// as a cookie all the same.
+func makeLinkExternalAccountEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ExternalLoginRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		authURL, state, err := s.StartExternalLogin(ctx, req.Provider, userID)
+		if err != nil {
+			return nil, err
+		}
+		return Redirect{URL: authURL, State: state, CookieMode: req.CookieMode}, nil
+	}
+}
+
+// ReauthExternal Endpoint. Returns the provider URL as JSON like
+// LinkExternalAccount; the flow ends with a token that confirms who the
+// user is instead of their password.
+func makeReauthExternalEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ExternalLoginRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		authURL, state, err := s.StartReauthentication(ctx, req.Provider, userID)
+		if err != nil {
+			return nil, err
+		}
+		return Redirect{URL: authURL, State: state}, nil
+	}
+}
+
+// ExternalLoginCallback Endpoint. Sends the browser back to the frontend
+// with our tokens (or an error) in the URL fragment, which is never sent
+// to any server. Flows started in cookie mode get their tokens as cookies
+// and only the lifetimes in the fragment.
+func makeExternalLoginCallbackEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(ExternalLoginRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		callback := strings.TrimRight(s.AppURL, "/") + "/oauth/callback#"
+		if req.Error != "" {
+			return Redirect{URL: callback + url.Values{"error": {req.Error}}.Encode()}, nil
+		}
+
+		result, err := s.CompleteExternalLogin(ctx, req.Provider, req.Code, req.State, req.BrowserState, clientInfo(ctx))
+		if err != nil {
+			return Redirect{URL: callback + callbackError(ctx, err).Encode()}, nil
+		}
+
+		if result.ReauthToken != "" {
+			return Redirect{URL: callback + url.Values{"reauth_token": {result.ReauthToken}}.Encode()}, nil
+		}
+
+		if result.MFARequired {
+			return Redirect{URL: callback + url.Values{
+				"mfa_required":    {"true"},
+				"challenge_token": {result.ChallengeToken},
+			}.Encode()}, nil
+		}
+
+		values := url.Values{
+			"expires_in":         {strconv.Itoa(result.ExpiresIn)},
+			"refresh_expires_in": {strconv.Itoa(result.RefreshExpiresIn)},
+		}
+		if result.MFASetupRequired {
+			values.Set("mfa_setup_required", "true")
+		}
+		if req.CookieMode {
+			values.Set("session", "cookie")
+			return Redirect{URL: callback + values.Encode(), Session: result.TokenPair}, nil
+		}
+		values.Set("token", result.Token)
+		values.Set("refresh_token", result.RefreshToken)
+		return Redirect{URL: callback + values.Encode()}, nil
+	}
+}
+
+// callbackError puts an error into the fragment of the callback, with the
+// details it encodes itself, like the ban and the appeal token of a banned
+// user, see transport.encodeError. Internal errors are logged and only
+// show up as "server_error", since the fragment stays in the history of
+// the browser.
+func callbackError(ctx context.Context, err error) url.Values {
+	if apperr.KindOf(err) == apperr.KindInternal {
+		log.Printf("request %s failed: %v", auth.GetRequestID(ctx), err)
+		return url.Values{"error": {"server_error"}}
+	}
+
+	values := url.Values{}
+	var marshaler json.Marshaler
+	if errors.As(err, &marshaler) {
+		if data, err := marshaler.MarshalJSON(); err == nil {
+			members := map[string]interface{}{}
+			json.Unmarshal(data, &members)
+			for name, value := range members {
+				if s, ok := value.(string); ok {
+					values.Set(name, s)
+				}
+			}
+		}
+	}
+	values.Set("error", err.Error())
+	return values
+}
+
+// GetMyIdentities Endpoint
+func makeGetMyIdentitiesEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetLinkedIdentities(userID)
+	}
+}
+
+// UnlinkIdentity Endpoint
+func makeUnlinkIdentityEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.UnlinkIdentity(userID, (*req)["provider"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "unlinked"}, nil
+	}
+}
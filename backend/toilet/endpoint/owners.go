@@ -0,0 +1,50 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// OwnerRequest names a user and the toilet whose venue they run
+type OwnerRequest struct {
+	ToiletID int `json:"toilet_id"`
+	UserID   int `json:"user_id"`
+}
+
+// VerifyOwner Endpoint (moderators only)
+func makeVerifyOwnerEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*OwnerRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.VerifyToiletOwner(req.ToiletID, req.UserID)
+	}
+}
+
+// RevokeOwner Endpoint (moderators only)
+func makeRevokeOwnerEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*OwnerRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		if err := s.RevokeToiletOwner(req.ToiletID, req.UserID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "revoked"}, nil
+	}
+}
+
+// auditOwner is the target of verifying and revoking an owner, see
+// models.TargetToiletOwner
+func auditOwner(_ context.Context, request, _ interface{}) string {
+	if req, ok := request.(*OwnerRequest); ok {
+		return fmt.Sprintf("%d:%d", req.ToiletID, req.UserID)
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// BanRequest bans a user. Days limits suspensions and shadow bans;
+// permanent bans take 0.
+type BanRequest struct {
+	UserID int    `json:"user_id"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	Days   int    `json:"days"`
+}
+
+// AppealRequest appeals the ban of an account. Banned users cannot sign
+// in, so the request carries the appeal token they got when they tried, or
+// their credentials.
+type AppealRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	AppealToken string `json:"appeal_token"`
+	Text        string `json:"text"`
+}
+
+// AppealsRequest pages through the open appeals
+type AppealsRequest struct {
+	Limit  int
+	Offset int
+}
+
+// DecideAppealRequest accepts or rejects an appeal. A response is required
+// for rejections.
+type DecideAppealRequest struct {
+	AppealID int    `json:"id"`
+	Accept   bool   `json:"accept"`
+	Response string `json:"response"`
+}
+
+// BanUser Endpoint (moderators only)
+func makeBanUserEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*BanRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		moderatorID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.BanUser(moderatorID, models.Ban{UserID: req.UserID, Kind: req.Kind, Reason: req.Reason}, req.Days)
+	}
+}
+
+// LiftBan Endpoint (moderators only)
+func makeLiftBanEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		reqMap, ok := request.(map[string]int)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		moderatorID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		if err := s.LiftBan(reqMap["id"], moderatorID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "lifted"}, nil
+	}
+}
+
+// UserBans Endpoint (moderators only)
+func makeUserBansEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		userID, ok := request.(string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userIDInt, err := strconv.Atoi(userID)
+		if err != nil {
+			return nil, apperr.Invalid("invalid user ID")
+		}
+		return s.GetUserBans(userIDInt)
+	}
+}
+
+// AppealBan Endpoint. Public, since banned users cannot sign in.
+func makeAppealBanEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AppealRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.AppealBan(req.Username, req.Password, req.AppealToken, req.Text, clientInfo(ctx))
+	}
+}
+
+// Appeals Endpoint (moderators only)
+func makeAppealsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(AppealsRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.OpenAppeals(req.Limit, req.Offset)
+	}
+}
+
+// DecideAppeal Endpoint (moderators only)
+func makeDecideAppealEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*DecideAppealRequest)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		moderatorID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		if err := s.DecideAppeal(req.AppealID, moderatorID, req.Accept, req.Response); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "decided"}, nil
+	}
+}
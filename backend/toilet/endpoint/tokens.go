@@ -0,0 +1,100 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// RefreshToken Endpoint
+func makeRefreshTokenEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		return s.RefreshTokens((*req)["refresh_token"])
+	}
+}
+
+// Logout Endpoint
+func makeLogoutEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		sessionID, ok := auth.GetSessionID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.Logout(userID, sessionID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "logged out"}, nil
+	}
+}
+
+// LogoutAll Endpoint
+func makeLogoutAllEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.LogoutAll(userID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "logged out"}, nil
+	}
+}
+
+// JWKS Endpoint
+func makeJWKSEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.PublicKeys(), nil
+	}
+}
+
+// GetMySessions Endpoint
+func makeGetMySessionsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		sessionID, _ := auth.GetSessionID(ctx)
+		return s.GetSessions(userID, sessionID)
+	}
+}
+
+// RevokeSession Endpoint. Signs out one of the user's devices.
+func makeRevokeSessionEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		sessionID, ok := request.(string)
+		if !ok || sessionID == "" {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.Logout(userID, sessionID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "revoked"}, nil
+	}
+}
+
+// clientInfo collects what the transport recorded about the client
+func clientInfo(ctx context.Context) service.ClientInfo {
+	return service.ClientInfo{IP: auth.GetClientIP(ctx), UserAgent: auth.GetUserAgent(ctx)}
+}
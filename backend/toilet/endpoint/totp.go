@@ -0,0 +1,96 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// CompleteLogin Endpoint. Accepts {"challenge_token": ..., "code": ...}
+// where code is a TOTP code or a recovery code.
+func makeCompleteLoginEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+		return s.CompleteLogin((*req)["challenge_token"], (*req)["code"], clientInfo(ctx))
+	}
+}
+
+// GetTOTPStatus Endpoint
+func makeGetTOTPStatusEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetTOTPStatus(userID)
+	}
+}
+
+// EnrollTOTP Endpoint
+func makeEnrollTOTPEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.EnrollTOTP(userID)
+	}
+}
+
+// ConfirmTOTP Endpoint. Accepts {"code": ...} from the authenticator app.
+func makeConfirmTOTPEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.ConfirmTOTP(userID, (*req)["code"])
+	}
+}
+
+// DisableTOTP Endpoint. Accepts {"code": ...}, a TOTP or recovery code.
+func makeDisableTOTPEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+
+		if err := s.DisableTOTP(userID, (*req)["code"]); err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "disabled"}, nil
+	}
+}
+
+// RegenerateRecoveryCodes Endpoint. Accepts {"code": ...}.
+func makeRegenerateRecoveryCodesEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*map[string]string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.RegenerateRecoveryCodes(userID, (*req)["code"])
+	}
+}
@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/service"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// GetMe Endpoint
+func makeGetMeEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetUser(userID)
+	}
+}
+
+// GetMyReputation Endpoint. Shows users their score and what it is made of.
+func makeGetMyReputationEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetReputation(userID)
+	}
+}
+
+// UpdateMe Endpoint
+func makeUpdateMeEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		update, ok := request.(*models.ProfileUpdate)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.UpdateProfile(userID, *update)
+	}
+}
+
+// GetMyToilets Endpoint
+func makeGetMyToiletsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetUserToilets(userID)
+	}
+}
+
+// GetMyReviews Endpoint
+func makeGetMyReviewsEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userID, ok := auth.GetUserID(ctx)
+		if !ok {
+			return nil, apperr.Unauthorized("unauthorized")
+		}
+		return s.GetUserReviews(userID)
+	}
+}
+
+// GetUserProfile Endpoint
+func makeGetUserProfileEndpoint(s service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		userID, ok := request.(string)
+		if !ok {
+			return nil, apperr.Invalid("invalid request format")
+		}
+
+		userIDInt, err := strconv.Atoi(userID)
+		if err != nil {
+			return nil, apperr.Invalid("invalid user ID")
+		}
+		viewerID, _ := auth.GetUserID(ctx)
+		return s.GetPublicProfile(userIDInt, viewerID)
+	}
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes messages as .eml files into Dir, or to the log when
+// Dir is empty. It is meant for development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send stores a message
+func (m *FileMailer) Send(msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	data := format(m.From, msg)
+
+	if m.Dir == "" {
+		log.Printf("mail to %s:\n%s", msg.To, data)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o644)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,70 @@
+// Package mail sends transactional emails such as password reset links.
+// The service only depends on the Mailer interface; SMTP is used in
+// production and the file mailer in development and tests.
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// validate rejects messages whose headers could be used for header injection
+func validate(msg Message) error {
+	if msg.To == "" {
+		return errors.New("message without recipient")
+	}
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return errors.New("invalid characters in message headers")
+	}
+	return nil
+}
+
+// NewMailerFromEnv picks a mailer based on the environment:
+//
+//   - SMTP_HOST (with SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM)
+//     sends real email, e.g. through a local SMTP catcher like MailHog,
+//   - MAIL_DIR writes every message as a file into that directory,
+//   - otherwise messages are written to the log.
+func NewMailerFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@free-toilet-map.local"
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 25
+		}
+		return &SMTPMailer{
+			Addr:     fmt.Sprintf("%s:%d", host, port),
+			Host:     host,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	if dir := os.Getenv("MAIL_DIR"); dir != "" {
+		return &FileMailer{Dir: dir, From: from}
+	}
+
+	log.Println("WARNING: no mailer configured, emails will only be logged")
+	return &FileMailer{From: from}
+}
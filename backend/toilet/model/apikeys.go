@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// APIKey is a key issued to a third-party application. The key itself is
+// only returned once, when it is created.
+type APIKey struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	Organization string     `json:"organization"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Key          string     `json:"key,omitempty"`
+	Scopes       []string   `json:"scopes"`
+	DailyQuota   int        `json:"daily_quota"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
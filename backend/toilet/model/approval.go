@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Approval states of new toilets. Pending toilets are shown only to their
+// founder and moderators, or to everyone marked as unverified, depending
+// on the configuration.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// PendingToilet is a toilet waiting for approval, as listed for moderators
+type PendingToilet struct {
+	Toilet
+	Confirmations int `json:"confirmations"`
+	// CreatedAt is nil for toilets added before creation times were kept
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ToiletConfirmation is the result of a user confirming a pending toilet
+type ToiletConfirmation struct {
+	ToiletID      int    `json:"toilet_id"`
+	Confirmations int    `json:"confirmations"`
+	Required      int    `json:"required"`
+	Approval      string `json:"approval"`
+}
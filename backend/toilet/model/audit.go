@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Targets of audit entries besides the reportable kinds of content
+const (
+	TargetList           = "list"
+	TargetAPIKey         = "api_key"
+	TargetModerationItem = "moderation_item"
+	TargetReport         = "report"
+	TargetSession        = "session"
+	TargetLogin          = "login" // a username or client IP locked out of logging in
+	TargetBan            = "ban"
+	TargetAppeal         = "appeal"
+	TargetToiletOwner    = "toilet_owner" // identified as "toilet_id:user_id"
+)
+
+// AuditEntry records a state-changing operation. Before and After are
+// snapshots of the target and are null where there is none, e.g. before
+// it was created or after it was deleted.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorID    *int            `json:"actor_id"`
+	APIKeyID   *int            `json:"api_key_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+}
+
+// AuditFilter selects audit entries. Zero values match everything.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
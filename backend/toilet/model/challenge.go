@@ -0,0 +1,9 @@
+package models
+
+// AbuseSignals are the signs of scripted registrations and logins the
+// difficulty of proof-of-work challenges is raised for
+type AbuseSignals struct {
+	IPFailures      int // consecutive failed logins from the client IP
+	AccountFailures int // failed logins on all accounts recently
+	Signups         int // accounts registered recently
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Comment statuses
+const (
+	CommentVisible = "visible"
+	CommentHidden  = "hidden"
+)
+
+// Author roles attached to comments written by the people behind a toilet
+const (
+	AuthorRoleFounder = "founder" // the user who added the toilet
+	AuthorRoleOwner   = "owner"   // a verified owner of the venue
+)
+
+// Comment is a reply under a review. Comments are not threaded further.
+type Comment struct {
+	ID         int        `json:"id"`
+	ReviewID   int        `json:"review_id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	AuthorRole string     `json:"author_role,omitempty"`
+	Text       string     `json:"text"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	// HoldReasons are why a hook held the comment back. It is queued for
+	// moderation with them once it is stored.
+	HoldReasons []string `json:"-"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// UserExport is everything we store about a user, as handed out by
+// GET /me/export. Every table that keeps personal data must show up here.
+type UserExport struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	Profile    User             `json:"profile"`
+	Toilets    []Toilet         `json:"toilets"`
+	Reviews    []Review         `json:"reviews"`
+	Comments   []Comment        `json:"comments"`
+	Lists      []ToiletList     `json:"lists"`
+	Identities []LinkedIdentity `json:"identities"`
+	APIKeys    []APIKey         `json:"api_keys"`
+	Ownerships []ToiletOwner    `json:"ownerships"` // venues the user was verified to run
+	Sessions   []Session        `json:"sessions"`
+	TwoFactor  TOTPStatus       `json:"two_factor"`
+	Reports    []Report         `json:"reports"` // filed by the user
+	Warnings   []Warning        `json:"warnings"`
+	Bans       []Ban            `json:"bans"`
+	Appeals    []Appeal         `json:"appeals"`
+	// AuditLog lists what the user did, without the snapshots of the
+	// targets, which may hold data of other users
+	AuditLog []AuditEntry `json:"audit_log"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FeatureCollection is a minimal GeoJSON (RFC 7946) feature collection
+type FeatureCollection struct {
+	Type       string                 `json:"type"`
+	Features   []Feature              `json:"features"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// ParsePoint splits a "lat,lng" point string into its coordinates
+func ParsePoint(point string) (lat, lng float64, ok bool) {
+	parts := strings.Split(point, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lng, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+// ToiletFeature converts a toilet into a GeoJSON point feature.
+// GeoJSON coordinates are ordered longitude first.
+func ToiletFeature(t Toilet) Feature {
+	f := Feature{
+		Type: "Feature",
+		Properties: map[string]interface{}{
+			"id":         t.ID,
+			"founder_id": t.FounderID,
+			"name":       t.Name,
+			"type":       t.Type,
+			"gender":     t.Gender,
+			"address":    t.Address,
+		},
+	}
+	if lat, lng, ok := ParsePoint(t.Point); ok {
+		f.Geometry = &Geometry{Type: "Point", Coordinates: []float64{lng, lat}}
+	}
+	return f
+}
+
+// ListFeatureCollection converts a saved list into a GeoJSON feature
+// collection, keeping the item order and notes as feature properties
+func ListFeatureCollection(list ToiletList) FeatureCollection {
+	fc := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+		Properties: map[string]interface{}{
+			"name": list.Name,
+			"slug": list.Slug,
+		},
+	}
+	for _, item := range list.Items {
+		if item.Toilet == nil {
+			continue
+		}
+		f := ToiletFeature(*item.Toilet)
+		f.Properties["position"] = item.Position
+		f.Properties["note"] = item.Note
+		fc.Features = append(fc.Features, f)
+	}
+	return fc
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// LinkedIdentity is an external account a user can sign in with
+type LinkedIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCState is a pending "Sign in with..." request
+type OIDCState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   int // set when an already signed-in user links an account
+	ReauthUserID int // set when a signed-in user confirms who they are
+	ExpiresAt    time.Time
+}
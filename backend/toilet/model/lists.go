@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// List visibility levels. Unlisted lists can be opened by anyone who knows
+// the slug, public lists may additionally be shown on the owner's profile.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+type ToiletList struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Visibility string     `json:"visibility"`
+	Slug       string     `json:"slug"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Items      []ListItem `json:"items"`
+}
+
+type ListItem struct {
+	ListID   int       `json:"list_id"`
+	ToiletID int       `json:"toilet_id"`
+	Position int       `json:"position"`
+	Note     string    `json:"note"`
+	AddedAt  time.Time `json:"added_at"`
+	Toilet   *Toilet   `json:"toilet,omitempty"`
+}
+
+// ValidVisibility reports whether v is one of the known visibility levels
+func ValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package models
+
+// DeviceLocation is where the client was when it submitted a toilet or
+// review, as reported by the device
+type DeviceLocation struct {
+	Lat      float64 `json:"lat"`
+	Lng      float64 `json:"lng"`
+	Accuracy float64 `json:"accuracy"` // radius in meters
+}
+
+// Results of comparing the device location with the toilet. The result is
+// unknown if the client sent no location.
+const (
+	LocationNear    = "near"
+	LocationRemote  = "remote"
+	LocationUnknown = "unknown"
+)
+
+// LocationCheck is the result of comparing the device location of a
+// submission with the point of the toilet
+type LocationCheck struct {
+	Result   string  // LocationNear, LocationRemote or LocationUnknown
+	Distance float64 // meters between the device and the toilet
+	Accuracy float64 // of the device location, in meters
+}
+
+// ReasonLocation marks reports filed for submissions made far away from
+// the toilet, or without a location
+const ReasonLocation = "location"
@@ -3,20 +3,36 @@ package models
 import "time"
 
 type User struct {
-	ID           int    `json:"id"`
-	Username     string `json:"username"`
-	Password     string `json:"-"`
-	ToiletsFound int    `json:"toilets_found"`
+	ID                int       `json:"id"`
+	Username          string    `json:"username"`
+	Password          string    `json:"-"`
+	ToiletsFound      int       `json:"toilets_found"`
+	DisplayName       string    `json:"display_name"`
+	AvatarURL         string    `json:"avatar_url"`
+	Bio               string    `json:"bio"`
+	PreferredLanguage string    `json:"preferred_language"`
+	Role              string    `json:"role"`
+	Email             string    `json:"email,omitempty"`
+	EmailVerified     bool      `json:"email_verified"`
+	CreatedAt         time.Time `json:"created_at"`
 }
 
 type Toilet struct {
-	ID        int    `json:"id"`
-	FounderID int    `json:"founder_id"`
-	Name      string `json:"name"`
-	Point     string `json:"point"` // "lat,lng"
-	Type      string `json:"type"`
-	Gender    string `json:"gender"`
-	Address   string `json:"address"`
+	ID             int      `json:"id"`
+	FounderID      int      `json:"founder_id"`
+	Name           string   `json:"name"`
+	Point          string   `json:"point"` // "lat,lng"
+	Type           string   `json:"type"`
+	Gender         string   `json:"gender"`
+	Address        string   `json:"address"`
+	Status         string   `json:"status,omitempty"`          // only set for the founder
+	Approval       string   `json:"approval,omitempty"`        // see ApprovalPending
+	ApprovalReason string   `json:"approval_reason,omitempty"` // why the toilet was rejected, for the founder
+	Rating         *float64 `json:"rating,omitempty"`          // only set in the list of all toilets
+	Remote         bool     `json:"remote,omitempty"`          // added far away from the toilet
+
+	Location      *DeviceLocation `json:"location,omitempty"` // sent by the client when adding the toilet
+	LocationCheck *LocationCheck  `json:"-"`
 }
 
 type Review struct {
@@ -27,6 +43,10 @@ type Review struct {
 	ReviewText string    `json:"review_text"`
 	CreatedAt  time.Time `json:"created_at"` // Дата создания отзыва
 	Score      float32   `json:"score"`
-	Username   string    `json:"username"` // Имя пользователя
+	Username   string    `json:"username"`         // Имя пользователя
+	Status     string    `json:"status,omitempty"` // only set for the author
+	Remote     bool      `json:"remote,omitempty"` // written far away from the toilet
 
+	Location      *DeviceLocation `json:"location,omitempty"` // sent by the client when adding the review
+	LocationCheck *LocationCheck  `json:"-"`
 }
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// Statuses of toilets and reviews. Hidden and pending content is kept, but
+// only shown to its author and to moderators.
+const (
+	StatusVisible = "visible"
+	StatusHidden  = "hidden"
+	StatusPending = "pending" // held back by the content filter
+)
+
+// Kinds of content that can be reported
+const (
+	TargetToilet  = "toilet"
+	TargetReview  = "review"
+	TargetComment = "comment"
+	TargetUser    = "user"
+)
+
+// States of a moderation queue item
+const (
+	ItemOpen      = "open"
+	ItemActioned  = "actioned"
+	ItemDismissed = "dismissed"
+)
+
+// ReasonScreening marks reports filed by the content filter for content it
+// held back
+const ReasonScreening = "screening"
+
+// ReasonAnomaly marks reports filed for reviews that are part of a
+// suspicious rating pattern
+const ReasonAnomaly = "anomaly"
+
+// Suspicious rating patterns
+const (
+	AnomalyBurst = "burst" // many low scores on one toilet from new accounts
+	AnomalySpree = "spree" // one user leaving many extreme scores in minutes
+)
+
+// Report is a complaint of a user about a toilet, review, comment or user
+type Report struct {
+	ID         int       `json:"id"`
+	ItemID     int       `json:"item_id"`
+	TargetType string    `json:"target_type"`
+	TargetID   int       `json:"target_id"`
+	ReporterID *int      `json:"reporter_id,omitempty"` // nil for reports of the content filter
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ModerationItem collects all reports about one target while it waits for
+// a moderator. Reports and Notes are only filled in when a single item is
+// requested.
+type ModerationItem struct {
+	ID          int              `json:"id"`
+	TargetType  string           `json:"target_type"`
+	TargetID    int              `json:"target_id"`
+	State       string           `json:"state"`
+	AssigneeID  *int             `json:"assignee_id,omitempty"`
+	Action      string           `json:"action,omitempty"`
+	ResolvedBy  *int             `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time       `json:"resolved_at,omitempty"`
+	ReportCount int              `json:"report_count"`
+	Priority    float64          `json:"priority"` // reports weighted by the reputation of the reporters
+	Reasons     []string         `json:"reasons"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Reports     []Report         `json:"reports,omitempty"`
+	Notes       []ModerationNote `json:"notes,omitempty"`
+}
+
+// ModerationNote is a remark of a moderator on a queue item
+type ModerationNote struct {
+	ID        int       `json:"id"`
+	ItemID    int       `json:"item_id"`
+	AuthorID  *int      `json:"author_id,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModerationFilter narrows down the moderation queue. Zero values match
+// everything.
+type ModerationFilter struct {
+	State      string
+	TargetType string
+	Reason     string
+	AssigneeID int
+	Unassigned bool
+	Limit      int
+	Offset     int
+}
+
+// Resolution closes a queue item, see Service.ResolveModerationItem
+type Resolution struct {
+	ItemID int    `json:"id"`
+	Action string `json:"action"`
+	Reason string `json:"reason"` // shown to the user for warn and ban
+	Note   string `json:"note"`
+	// BanDays limits a ban or shadow ban; 0 bans permanently
+	BanDays int `json:"ban_days"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ToiletOwner is a user a moderator has confirmed to run the venue of a
+// toilet. Their comments are marked with AuthorRoleOwner.
+type ToiletOwner struct {
+	ToiletID   int       `json:"toilet_id"`
+	UserID     int       `json:"user_id"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
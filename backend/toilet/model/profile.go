@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ProfileUpdate holds the fields a user may change on their own profile.
+// Nil fields are left untouched.
+type ProfileUpdate struct {
+	DisplayName       *string `json:"display_name"`
+	AvatarURL         *string `json:"avatar_url"`
+	Bio               *string `json:"bio"`
+	PreferredLanguage *string `json:"preferred_language"`
+}
+
+// PublicProfile is the part of a user that is visible to everyone
+type PublicProfile struct {
+	ID           int          `json:"id"`
+	Username     string       `json:"username"`
+	DisplayName  string       `json:"display_name"`
+	AvatarURL    string       `json:"avatar_url"`
+	Bio          string       `json:"bio"`
+	ToiletsFound int          `json:"toilets_found"`
+	ReviewsCount int          `json:"reviews_count"`
+	Reputation   int          `json:"reputation"`
+	Level        string       `json:"level"` // see Reputation.Level
+	CreatedAt    time.Time    `json:"created_at"`
+	Lists        []ToiletList `json:"lists"`
+}
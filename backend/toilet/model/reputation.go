@@ -0,0 +1,30 @@
+package models
+
+// Reputation levels
+const (
+	ReputationNew     = "new"     // contributions are limited per day
+	ReputationRegular = "regular" // contributions go through the content filter
+	ReputationTrusted = "trusted" // published right away, without screening holds or toilet approval
+)
+
+// Reputation tells how much a contributor can be trusted. Score ranges
+// from 0 to 100.
+type Reputation struct {
+	UserID  int               `json:"user_id"`
+	Score   int               `json:"score"`
+	Level   string            `json:"level"`
+	Factors ReputationFactors `json:"factors"`
+}
+
+// ReputationFactors are the facts the score is computed from
+type ReputationFactors struct {
+	AccountAgeDays   int    `json:"account_age_days"`
+	Role             string `json:"-"`
+	PublishedToilets int    `json:"published_toilets"`
+	PublishedReviews int    `json:"published_reviews"`
+	ConfirmedReports int    `json:"confirmed_reports"` // reports that led to moderator action
+	DismissedReports int    `json:"dismissed_reports"`
+	RemovedContent   int    `json:"removed_content"` // content hidden or deleted by moderators and rejected toilets
+	Warnings         int    `json:"warnings"`
+	Bans             int    `json:"bans"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Kinds of bans
+const (
+	BanSuspension = "suspension" // the account is locked for a period
+	BanPermanent  = "ban"        // the account is locked for good
+	BanShadow     = "shadow_ban" // the account works, but only its owner sees its content
+)
+
+// Ban is a sanction a moderator imposed on a user
+type Ban struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Kind        string     `json:"kind"`
+	Reason      string     `json:"reason"`
+	ItemID      *int       `json:"item_id,omitempty"` // the queue item that led to the ban
+	ModeratorID *int       `json:"moderator_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // nil for bans without end
+	LiftedAt    *time.Time `json:"lifted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Warning is a notice a moderator sent to a user whose content was
+// reported
+type Warning struct {
+	ID          int       `json:"id"`
+	ItemID      *int      `json:"item_id,omitempty"` // the queue item that led to the warning
+	ModeratorID *int      `json:"moderator_id,omitempty"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Appeal states
+const (
+	AppealOpen     = "open"
+	AppealAccepted = "accepted" // the ban was lifted
+	AppealRejected = "rejected"
+)
+
+// Appeal is a user's request to lift a ban. Each ban can be appealed once.
+type Appeal struct {
+	ID        int        `json:"id"`
+	BanID     int        `json:"ban_id"`
+	UserID    int        `json:"user_id"`
+	Text      string     `json:"text"`
+	State     string     `json:"state"`
+	Response  string     `json:"response,omitempty"` // the moderator's answer
+	DecidedBy *int       `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Ban       *Ban       `json:"ban,omitempty"` // set when listed for moderators
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is a signed-in device of a user as listed by GET /me/sessions
+type Session struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"` // short description derived from the user agent
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"` // the session the request was made with
+	// RevokedAt is only filled in for the data export, which includes
+	// ended sessions
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// TokenPair is returned on login and on every refresh
+type TokenPair struct {
+	Token        string `json:"token"` // access token
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+	// RefreshExpiresIn is the refresh token lifetime in seconds
+	RefreshExpiresIn int `json:"refresh_expires_in,omitempty"`
+	// MFASetupRequired is set for moderators and admins without 2FA. Their
+	// tokens only carry user permissions until they enable it.
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// LoginResult is the response of a login. Users with two-factor
+// authentication get a challenge instead of tokens, which is completed with
+// a code at /login/2fa.
+type LoginResult struct {
+	*TokenPair
+	MFARequired        bool   `json:"mfa_required,omitempty"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+	ChallengeExpiresIn int    `json:"challenge_expires_in,omitempty"`
+	// ReauthToken ends a flow started to confirm who a signed-in user is,
+	// see Service.StartReauthentication
+	ReauthToken string `json:"reauth_token,omitempty"`
+}
+
+// TOTPStatus is the two-factor authentication state of a user
+type TOTPStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"` // the user's role may not go without it
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment is what an authenticator app needs to be set up
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodes are shown to the user once, when they are generated
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+)
+
+// DeletedUsername is the system user that takes over the community content
+// of deleted accounts
+const DeletedUsername = "deleted_user"
+
+// GetCommentsByUser retrieves all comments written by a user, including hidden ones
+func (r *PostgresRepository) GetCommentsByUser(userID int) ([]models.Comment, error) {
+	rows, err := r.db.Query(`SELECT `+commentColumns+commentJoins+`
+        WHERE c.user_id = $1
+        ORDER BY c.created_at, c.id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteUser deletes a user account. Toilets, reviews and comments are
+// reassigned to the deleted_user system account so that the community
+// keeps them; everything else personal is removed with the user.
+func (r *PostgresRepository) DeleteUser(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var systemID int
+	if err := tx.QueryRow(`SELECT id FROM users WHERE username = $1`, DeletedUsername).Scan(&systemID); err != nil {
+		return err
+	}
+	if systemID == userID {
+		return apperr.Forbidden("cannot delete the system user")
+	}
+
+	for _, query := range []string{
+		`UPDATE toilets SET founder_id = $1 WHERE founder_id = $2`,
+		`UPDATE reviews SET user_id = $1 WHERE user_id = $2`,
+		`UPDATE review_comments SET user_id = $1 WHERE user_id = $2`,
+	} {
+		if _, err := tx.Exec(query, systemID, userID); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(result, apperr.NotFound("user not found")); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
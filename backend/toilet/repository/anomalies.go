@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetLowScoresFromNewAccounts returns the reviews of a toilet written
+// since a time with a score of at most maxScore by accounts created after
+// accountsSince, and how many distinct accounts wrote them. Hidden reviews
+// and reviews a moderator cleared are left out.
+func (r *PostgresRepository) GetLowScoresFromNewAccounts(toiletID int, since time.Time, maxScore float32, accountsSince time.Time) ([]int, int, error) {
+	rows, err := r.db.Query(`
+        SELECT r.id, r.user_id
+        FROM reviews r
+        JOIN users u ON u.id = r.user_id
+        WHERE r.toilet_id = $1 AND r.created_at >= $2 AND r.score <= $3
+            AND u.created_at >= $4 AND r.status <> 'hidden' AND r.anomaly_cleared_at IS NULL
+    `, toiletID, since, maxScore, accountsSince)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	users := map[int]bool{}
+	for rows.Next() {
+		var id, userID int
+		if err := rows.Scan(&id, &userID); err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+		users[userID] = true
+	}
+	return ids, len(users), rows.Err()
+}
+
+// GetRatingBaseline returns the number and average score of the published,
+// unflagged reviews a toilet had before a time
+func (r *PostgresRepository) GetRatingBaseline(toiletID int, before time.Time) (int, float64, error) {
+	var count int
+	var mean float64
+	err := r.db.QueryRow(`
+        SELECT COUNT(*), COALESCE(AVG(score), 0)
+        FROM reviews
+        WHERE toilet_id = $1 AND created_at < $2 AND status = 'visible' AND anomaly IS NULL
+    `, toiletID, before).Scan(&count, &mean)
+	return count, mean, err
+}
+
+// GetExtremeScoresSince returns the reviews of a user written since a time
+// with a score of at most low or at least high, except those a moderator
+// cleared
+func (r *PostgresRepository) GetExtremeScoresSince(userID int, since time.Time, low, high float32) ([]int, error) {
+	rows, err := r.db.Query(`
+        SELECT id
+        FROM reviews
+        WHERE user_id = $1 AND created_at >= $2 AND (score <= $3 OR score >= $4)
+            AND anomaly_cleared_at IS NULL
+    `, userID, since, low, high)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FlagReviewAnomalies marks reviews as part of a suspicious pattern and
+// returns those that were not flagged before. Reviews a moderator cleared
+// are not flagged again.
+func (r *PostgresRepository) FlagReviewAnomalies(reviewIDs []int, anomaly string) ([]int, error) {
+	ids := make([]int64, len(reviewIDs))
+	for i, id := range reviewIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := r.db.Query(`
+        UPDATE reviews SET anomaly = $2
+        WHERE id = ANY($1) AND anomaly IS NULL AND anomaly_cleared_at IS NULL
+        RETURNING id
+    `, pq.Array(ids), anomaly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flagged []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, id)
+	}
+	return flagged, rows.Err()
+}
+
+// ClearReviewAnomaly removes the flag of a review a moderator found fine,
+// so that it counts towards the rating again, and remembers that it was
+// cleared so that it is not flagged again
+func (r *PostgresRepository) ClearReviewAnomaly(reviewID int, now time.Time) error {
+	_, err := r.db.Exec(`UPDATE reviews SET anomaly = NULL, anomaly_cleared_at = $2 WHERE id = $1`, reviewID, now)
+	return err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `
+    id, user_id, organization, name, prefix, scopes, daily_quota, created_at, last_used_at, revoked_at
+`
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(
+		&k.ID, &k.UserID, &k.Organization, &k.Name, &k.Prefix, pq.Array(&k.Scopes),
+		&k.DailyQuota, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt,
+	)
+	return k, err
+}
+
+// CreateAPIKey stores a new API key by its hash
+func (r *PostgresRepository) CreateAPIKey(key models.APIKey, keyHash string) (models.APIKey, error) {
+	err := r.db.QueryRow(`
+        INSERT INTO api_keys (user_id, organization, name, prefix, key_hash, scopes, daily_quota)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at
+    `, key.UserID, key.Organization, key.Name, key.Prefix, keyHash, pq.Array(key.Scopes), key.DailyQuota,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// GetAPIKeysByUser retrieves all keys of a user, including revoked ones
+func (r *PostgresRepository) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	rows, err := r.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CountActiveAPIKeys returns the number of keys of a user that are not revoked
+func (r *PostgresRepository) CountActiveAPIKeys(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// GetActiveAPIKeyByHash retrieves a key that has not been revoked by its hash
+func (r *PostgresRepository) GetActiveAPIKeyByHash(keyHash string) (models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, keyHash))
+	if err == sql.ErrNoRows {
+		return k, apperr.Unauthorized("invalid api key")
+	}
+	return k, err
+}
+
+// RecordAPIKeyUsage counts a request against the key's quota for the given
+// day, updates its last use and returns the number of requests that day
+func (r *PostgresRepository) RecordAPIKeyUsage(keyID int, now time.Time) (int, error) {
+	var requests int
+	err := r.db.QueryRow(`
+        INSERT INTO api_key_usage (key_id, day, requests)
+        VALUES ($1, $2::date, 1)
+        ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+        RETURNING requests
+    `, keyID, now.Format("2006-01-02")).Scan(&requests)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, now, keyID)
+	return requests, err
+}
+
+// RevokeAPIKey revokes a key of a user
+func (r *PostgresRepository) RevokeAPIKey(keyID, userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, keyID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("api key not found"))
+}
+
+// SetAPIKeyQuota changes the daily quota of any key
+func (r *PostgresRepository) SetAPIKeyQuota(keyID, dailyQuota int) error {
+	result, err := r.db.Exec(`UPDATE api_keys SET daily_quota = $1 WHERE id = $2`, dailyQuota, keyID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("api key not found"))
+}
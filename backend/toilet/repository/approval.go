@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// ErrToiletNotFound is returned when a toilet does not exist
+var ErrToiletNotFound = apperr.NotFound("toilet not found")
+
+// ErrAlreadyConfirmed is returned when a user confirms a toilet twice
+var ErrAlreadyConfirmed = apperr.Conflict("you have already confirmed this toilet")
+
+// GetToilet retrieves a toilet with its status and approval
+func (r *PostgresRepository) GetToilet(toiletID int) (models.Toilet, error) {
+	var t models.Toilet
+	err := r.db.QueryRow(`
+        SELECT id, founder_id, name, point, type, gender, address, status, approval, approval_reason
+        FROM toilets
+        WHERE id = $1
+    `, toiletID).Scan(&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address,
+		&t.Status, &t.Approval, &t.ApprovalReason)
+	if err == sql.ErrNoRows {
+		return t, ErrToiletNotFound
+	}
+	return t, err
+}
+
+// AddToiletConfirmation records that a user confirmed a pending toilet and
+// returns how many users have confirmed it. Confirmations of shadow-banned
+// users are kept but not counted.
+func (r *PostgresRepository) AddToiletConfirmation(toiletID, userID int) (int, error) {
+	err := r.db.QueryRow(`
+        INSERT INTO toilet_confirmations (toilet_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT (toilet_id, user_id) DO NOTHING
+        RETURNING toilet_id
+    `, toiletID, userID).Scan(&toiletID)
+	if err == sql.ErrNoRows {
+		return 0, ErrAlreadyConfirmed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = r.db.QueryRow(`
+        SELECT COUNT(*) FROM toilet_confirmations
+        WHERE toilet_id = $1 AND user_id NOT IN (SELECT user_id FROM shadow_banned_users)
+    `, toiletID).Scan(&count)
+	return count, err
+}
+
+// SetToiletApproval moves a toilet from one approval state to another.
+// decidedBy is nil for approval by the community. It fails if the toilet
+// is not in the from state, so that concurrent decisions cannot both win.
+func (r *PostgresRepository) SetToiletApproval(toiletID int, from, to, reason string, decidedBy *int, now time.Time) error {
+	result, err := r.db.Exec(`
+        UPDATE toilets
+        SET approval = $3, approval_reason = $4, approval_by = $5, approval_at = $6
+        WHERE id = $1 AND approval = $2
+    `, toiletID, from, to, reason, decidedBy, now)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("toilet not found or already decided"))
+}
+
+// ResetToiletApproval puts a toilet back into the pending state and drops
+// its confirmations, e.g. after it was moved
+func (r *PostgresRepository) ResetToiletApproval(toiletID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        UPDATE toilets
+        SET approval = 'pending', approval_reason = '', approval_by = NULL, approval_at = NULL
+        WHERE id = $1
+    `, toiletID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM toilet_confirmations WHERE toilet_id = $1`, toiletID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPendingToilets lists toilets waiting for approval, oldest first.
+// Toilets held back by the content filter are left out; they are in the
+// moderation queue.
+func (r *PostgresRepository) GetPendingToilets(limit, offset int) ([]models.PendingToilet, error) {
+	rows, err := r.db.Query(`
+        SELECT t.id, t.founder_id, t.name, t.point, t.type, t.gender, t.address, t.status, t.approval,
+            (SELECT COUNT(*) FROM toilet_confirmations c WHERE c.toilet_id = t.id),
+            t.created_at
+        FROM toilets t
+        WHERE t.approval = 'pending' AND t.status = 'visible'
+        ORDER BY t.created_at NULLS FIRST, t.id
+        LIMIT $1 OFFSET $2
+    `, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	toilets := []models.PendingToilet{}
+	for rows.Next() {
+		var t models.PendingToilet
+		var createdAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address,
+			&t.Status, &t.Approval, &t.Confirmations, &createdAt); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = nullTimePtr(createdAt)
+		toilets = append(toilets, t)
+	}
+	return toilets, rows.Err()
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	models "free_toilet_map/toilet/model"
+	"strings"
+)
+
+// auditSnapshotQueries read a target as JSON for the audit log. Secrets
+// are left out. Audit entries outlive deleted accounts, so users are only
+// recorded with columns that say nothing about the person behind them.
+var auditSnapshotQueries = map[string]string{
+	models.TargetToilet:         `SELECT to_jsonb(t) FROM toilets t WHERE t.id = $1`,
+	models.TargetReview:         `SELECT to_jsonb(r) FROM reviews r WHERE r.id = $1`,
+	models.TargetComment:        `SELECT to_jsonb(c) FROM review_comments c WHERE c.id = $1`,
+	models.TargetAPIKey:         `SELECT to_jsonb(k) - 'key_hash' FROM api_keys k WHERE k.id = $1`,
+	models.TargetModerationItem: `SELECT to_jsonb(i) FROM moderation_items i WHERE i.id = $1`,
+	models.TargetReport:         `SELECT to_jsonb(rp) FROM reports rp WHERE rp.id = $1`,
+	models.TargetBan:            `SELECT to_jsonb(b) FROM user_bans b WHERE b.id = $1`,
+	models.TargetAppeal:         `SELECT to_jsonb(a) FROM ban_appeals a WHERE a.id = $1`,
+	models.TargetToiletOwner: `
+        SELECT to_jsonb(o) FROM toilet_owners o
+        WHERE o.toilet_id = split_part($1, ':', 1)::int AND o.user_id = split_part($1, ':', 2)::int`,
+	models.TargetUser: `
+        SELECT jsonb_build_object(
+            'id', u.id,
+            'role', u.role,
+            'email_verified', u.email_verified_at IS NOT NULL,
+            'created_at', u.created_at)
+        FROM users u WHERE u.id = $1`,
+	models.TargetList: `
+        SELECT to_jsonb(l) || jsonb_build_object('items', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(li) ORDER BY li.position, li.toilet_id)
+             FROM toilet_list_items li WHERE li.list_id = l.id), '[]'::jsonb))
+        FROM toilet_lists l WHERE l.id = $1`,
+}
+
+// GetAuditSnapshot returns the current state of a target as JSON. It
+// returns nil if the target does not exist or cannot be snapshotted.
+func (r *PostgresRepository) GetAuditSnapshot(targetType, targetID string) (json.RawMessage, error) {
+	query, ok := auditSnapshotQueries[targetType]
+	if !ok || targetID == "" {
+		return nil, nil
+	}
+
+	var snapshot []byte
+	err := r.db.QueryRow(query, targetID).Scan(&snapshot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return snapshot, err
+}
+
+// AddAuditEntry appends an entry to the audit log
+func (r *PostgresRepository) AddAuditEntry(entry models.AuditEntry) error {
+	_, err := r.db.Exec(`
+        INSERT INTO audit_log (actor_id, api_key_id, action, target_type, target_id, before, after, request_id, ip, user_agent)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    `, entry.ActorID, entry.APIKeyID, entry.Action, entry.TargetType, entry.TargetID,
+		jsonOrNull(entry.Before), jsonOrNull(entry.After), entry.RequestID, entry.IP, entry.UserAgent)
+	return err
+}
+
+// jsonOrNull passes JSON as text, which Postgres casts to JSONB, and nil as
+// NULL
+func jsonOrNull(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+// QueryAuditLog returns the audit entries matching a filter, newest first.
+// A zero Limit returns all of them.
+func (r *PostgresRepository) QueryAuditLog(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != 0 {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = $%d", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		where("request_id = $%d", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	query := `
+        SELECT id, created_at, actor_id, api_key_id, action, target_type, target_id,
+               before, after, request_id, ip, user_agent
+        FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var actorID, apiKeyID sql.NullInt64
+		var before, after []byte
+		if err := rows.Scan(
+			&e.ID, &e.CreatedAt, &actorID, &apiKeyID, &e.Action, &e.TargetType, &e.TargetID,
+			&before, &after, &e.RequestID, &e.IP, &e.UserAgent,
+		); err != nil {
+			return nil, err
+		}
+		e.ActorID = nullIntPtr(actorID)
+		e.APIKeyID = nullIntPtr(apiKeyID)
+		e.Before = before
+		e.After = after
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// GetAbuseSignals collects the signs of abuse for proof-of-work challenges.
+// Failures of the client IP count if the last one is after ipSince; failed
+// logins on accounts and new accounts count since since.
+func (r *PostgresRepository) GetAbuseSignals(clientIP string, ipSince, since time.Time) (models.AbuseSignals, error) {
+	var s models.AbuseSignals
+	err := r.db.QueryRow(`
+        SELECT
+            COALESCE((SELECT failures FROM login_throttle
+                      WHERE kind = $1 AND key = $2 AND last_failure_at >= $3), 0),
+            COALESCE((SELECT SUM(failures) FROM login_throttle
+                      WHERE kind = $4 AND last_failure_at >= $5), 0),
+            (SELECT COUNT(*) FROM users WHERE created_at >= $5)
+    `, ThrottleIP, clientIP, ipSince, ThrottleAccount, since).Scan(&s.IPFailures, &s.AccountFailures, &s.Signups)
+	return s, err
+}
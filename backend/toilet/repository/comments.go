@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+)
+
+// commentColumns selects a comment together with its author's name and
+// role. A verified venue owner takes precedence over the founder.
+const commentColumns = `
+    c.id, c.review_id, c.user_id, u.username,
+    CASE
+        WHEN EXISTS (
+            SELECT 1 FROM toilet_owners o
+            WHERE o.toilet_id = r.toilet_id AND o.user_id = c.user_id AND o.verified_at IS NOT NULL
+        ) THEN 'owner'
+        WHEN t.founder_id = c.user_id THEN 'founder'
+        ELSE ''
+    END,
+    c.text, c.status, c.created_at, c.updated_at
+`
+
+const commentJoins = `
+    FROM review_comments c
+    JOIN users u ON u.id = c.user_id
+    JOIN reviews r ON r.id = c.review_id
+    JOIN toilets t ON t.id = r.toilet_id
+`
+
+func scanComment(row rowScanner) (models.Comment, error) {
+	var c models.Comment
+	err := row.Scan(&c.ID, &c.ReviewID, &c.UserID, &c.Username, &c.AuthorRole, &c.Text, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// AddComment stores a reply under a review
+func (r *PostgresRepository) AddComment(comment models.Comment) (models.Comment, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO review_comments (review_id, user_id, text, status)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, comment.ReviewID, comment.UserID, comment.Text, comment.Status).Scan(&id)
+	if err != nil {
+		return models.Comment{}, dbError(err)
+	}
+	return r.GetComment(id)
+}
+
+// GetComment retrieves a single comment by its ID
+func (r *PostgresRepository) GetComment(commentID int) (models.Comment, error) {
+	c, err := scanComment(r.db.QueryRow(`SELECT `+commentColumns+commentJoins+` WHERE c.id = $1`, commentID))
+	if err == sql.ErrNoRows {
+		return c, apperr.NotFound("comment not found")
+	}
+	return c, err
+}
+
+// GetCommentsByReview retrieves the visible comments of a review, oldest
+// first. Comments of shadow-banned users are only shown to themselves.
+func (r *PostgresRepository) GetCommentsByReview(reviewID, viewerID int) ([]models.Comment, error) {
+	rows, err := r.db.Query(`SELECT `+commentColumns+commentJoins+`
+        WHERE c.review_id = $1 AND c.status = 'visible'
+            AND (c.user_id = $2 OR c.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+        ORDER BY c.created_at, c.id
+    `, reviewID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// UpdateComment changes the text of a comment written by userID. The
+// status is only changed to hide the comment, so that editing cannot
+// publish a comment a moderator hid.
+func (r *PostgresRepository) UpdateComment(comment models.Comment) error {
+	result, err := r.db.Exec(`
+        UPDATE review_comments
+        SET text = $1, status = CASE WHEN $2 = 'hidden' THEN 'hidden' ELSE status END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3 AND user_id = $4
+    `, comment.Text, comment.Status, comment.ID, comment.UserID)
+	if err != nil {
+		return err
+	}
+	return checkCommentAffected(result)
+}
+
+// DeleteComment deletes a comment written by userID
+func (r *PostgresRepository) DeleteComment(commentID, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM review_comments WHERE id = $1 AND user_id = $2`, commentID, userID)
+	if err != nil {
+		return err
+	}
+	return checkCommentAffected(result)
+}
+
+// SetCommentStatus hides or restores a comment regardless of its author
+func (r *PostgresRepository) SetCommentStatus(commentID int, status string) error {
+	result, err := r.db.Exec(`UPDATE review_comments SET status = $1 WHERE id = $2`, status, commentID)
+	if err != nil {
+		return err
+	}
+	return checkCommentAffected(result)
+}
+
+func checkCommentAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("not authorized or comment not found")
+	}
+	return nil
+}
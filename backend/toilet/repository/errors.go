@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"free_toilet_map/toilet/apperr"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrUsernameTaken is returned when a new user's name is taken
+	ErrUsernameTaken = apperr.Conflict("username already exists")
+	// ErrEmailTaken is returned when an email address belongs to another user
+	ErrEmailTaken = apperr.Conflict("email already in use")
+	// ErrIdentityLinked is returned when an external identity is already
+	// linked to a user
+	ErrIdentityLinked = apperr.Conflict("account is already linked to another user")
+)
+
+// constraintErrors tell clients which rule a write broke, by the name of
+// the unique constraint that refused it
+var constraintErrors = map[string]error{
+	"users_username_key":     ErrUsernameTaken,
+	"idx_users_username_key": ErrUsernameTaken,
+	"users_email_key":        ErrEmailTaken,
+	"user_identities_pkey":   ErrIdentityLinked,
+}
+
+// dbError translates the errors of writes the database refuses because of
+// a constraint into domain errors: duplicates are conflicts, references to
+// rows that do not exist are not found, and values out of range are
+// invalid. Other errors are returned unchanged.
+func dbError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		if known, ok := constraintErrors[pqErr.Constraint]; ok {
+			return known
+		}
+		return apperr.Wrap(apperr.KindConflict, "already exists", err)
+	case "foreign_key_violation":
+		// Deleting a row that is still referenced is a conflict, inserting
+		// a reference to a row that does not exist is not found
+		if strings.HasPrefix(pqErr.Message, "update or delete") {
+			return apperr.Wrap(apperr.KindConflict, "still in use", err)
+		}
+		return apperr.Wrap(apperr.KindNotFound, referencedName(pqErr.Detail)+" not found", err)
+	case "check_violation", "not_null_violation", "string_data_right_truncation",
+		"numeric_value_out_of_range", "invalid_text_representation":
+		return apperr.Wrap(apperr.KindInvalid, "invalid value", err)
+	case "serialization_failure", "deadlock_detected":
+		return apperr.Wrap(apperr.KindConflict, "the request clashed with another one, try again", err)
+	}
+	return err
+}
+
+// tableNames name the rows of the tables other tables refer to
+var tableNames = map[string]string{
+	"users":            "user",
+	"toilets":          "toilet",
+	"reviews":          "review",
+	"review_comments":  "comment",
+	"toilet_lists":     "list",
+	"api_keys":         "API key",
+	"sessions":         "session",
+	"moderation_items": "moderation item",
+	"user_bans":        "ban",
+	"user_identities":  "linked account",
+}
+
+// referencedName finds the missing row's table in the detail of a foreign
+// key violation, like `Key (toilet_id)=(5) is not present in table
+// "toilets".`, and names its rows
+func referencedName(detail string) string {
+	_, table, ok := strings.Cut(detail, `table "`)
+	if !ok {
+		return "referenced record"
+	}
+	table, _, _ = strings.Cut(table, `"`)
+	if name, ok := tableNames[table]; ok {
+		return name
+	}
+	return "referenced record"
+}
+
+// checkAffected returns notFound if a statement changed no rows
+func checkAffected(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// CreateOIDCState stores a pending authorization request. Requests that
+// were never completed are purged on the way.
+func (r *PostgresRepository) CreateOIDCState(st models.OIDCState, now time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM oidc_states WHERE expires_at < $1`, now); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+        INSERT INTO oidc_states (state, provider, nonce, code_verifier, link_user_id, reauth_user_id, expires_at)
+        VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7)
+    `, st.State, st.Provider, st.Nonce, st.CodeVerifier, st.LinkUserID, st.ReauthUserID, st.ExpiresAt)
+	return err
+}
+
+// ConsumeOIDCState removes and returns a pending authorization request.
+// Each state can only be used once.
+func (r *PostgresRepository) ConsumeOIDCState(state string, now time.Time) (models.OIDCState, error) {
+	var (
+		st           models.OIDCState
+		linkUserID   sql.NullInt64
+		reauthUserID sql.NullInt64
+	)
+	err := r.db.QueryRow(`
+        DELETE FROM oidc_states
+        WHERE state = $1
+        RETURNING state, provider, nonce, code_verifier, link_user_id, reauth_user_id, expires_at
+    `, state).Scan(&st.State, &st.Provider, &st.Nonce, &st.CodeVerifier, &linkUserID, &reauthUserID, &st.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return st, apperr.Invalid("invalid or expired login request")
+	}
+	if err != nil {
+		return st, err
+	}
+	if now.After(st.ExpiresAt) {
+		return st, apperr.Invalid("invalid or expired login request")
+	}
+
+	st.LinkUserID = int(linkUserID.Int64)
+	st.ReauthUserID = int(reauthUserID.Int64)
+	return st, nil
+}
+
+// GetUserByIdentity retrieves the user an external identity is linked to
+func (r *PostgresRepository) GetUserByIdentity(provider, subject string) (models.User, error) {
+	user, err := scanUser(r.db.QueryRow(`
+        SELECT `+userColumns+`
+        FROM users
+        JOIN user_identities i ON i.user_id = users.id
+        WHERE i.provider = $1 AND i.subject = $2
+    `, provider, subject))
+	if err == sql.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// LinkIdentity links an external identity to a user
+func (r *PostgresRepository) LinkIdentity(userID int, provider, subject, email string) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_identities (provider, subject, user_id, email)
+        VALUES ($1, $2, $3, $4)
+    `, provider, subject, userID, email)
+	return dbError(err)
+}
+
+// GetIdentitiesByUser retrieves the external identities linked to a user
+func (r *PostgresRepository) GetIdentitiesByUser(userID int) ([]models.LinkedIdentity, error) {
+	rows, err := r.db.Query(`
+        SELECT provider, subject, email, created_at
+        FROM user_identities
+        WHERE user_id = $1
+        ORDER BY created_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.LinkedIdentity{}
+	for rows.Next() {
+		var id models.LinkedIdentity
+		if err := rows.Scan(&id.Provider, &id.Subject, &id.Email, &id.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, id)
+	}
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity removes an external identity from a user
+func (r *PostgresRepository) UnlinkIdentity(userID int, provider string) error {
+	result, err := r.db.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("identity not found"))
+}
+
+// UsernameExists reports whether a username, or one that looks the same
+// (has the same key), is taken
+func (r *PostgresRepository) UsernameExists(username, usernameKey string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 OR username_key = $2)
+    `, username, usernameKey).Scan(&exists)
+	return exists, err
+}
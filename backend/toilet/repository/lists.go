@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+)
+
+// CreateList creates a new saved list for a user
+func (r *PostgresRepository) CreateList(list models.ToiletList) (models.ToiletList, error) {
+	query := `
+        INSERT INTO toilet_lists (user_id, name, visibility, slug)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+	err := r.db.QueryRow(query, list.UserID, list.Name, list.Visibility, list.Slug).Scan(&list.ID, &list.CreatedAt)
+	if err != nil {
+		return models.ToiletList{}, dbError(err)
+	}
+	list.Items = []models.ListItem{}
+	return list, nil
+}
+
+// UpdateList renames a list or changes its visibility
+func (r *PostgresRepository) UpdateList(list models.ToiletList) error {
+	result, err := r.db.Exec(`
+        UPDATE toilet_lists
+        SET name = $1, visibility = $2
+        WHERE id = $3 AND user_id = $4
+    `, list.Name, list.Visibility, list.ID, list.UserID)
+	if err != nil {
+		return err
+	}
+	return checkListAffected(result)
+}
+
+// DeleteList deletes a list together with its items
+func (r *PostgresRepository) DeleteList(listID, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM toilet_lists WHERE id = $1 AND user_id = $2`, listID, userID)
+	if err != nil {
+		return err
+	}
+	return checkListAffected(result)
+}
+
+// GetListsByUser retrieves all lists owned by a user, including their items
+func (r *PostgresRepository) GetListsByUser(userID int) ([]models.ToiletList, error) {
+	query := `
+        SELECT id, user_id, name, visibility, slug, created_at
+        FROM toilet_lists
+        WHERE user_id = $1
+        ORDER BY created_at, id
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.ToiletList{}
+	for rows.Next() {
+		var l models.ToiletList
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &l.Visibility, &l.Slug, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range lists {
+		items, err := r.getListItems(lists[i].ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		lists[i].Items = items
+	}
+	return lists, nil
+}
+
+// GetListBySlug retrieves a list and its items by slug, as seen by
+// viewerID, see getListItems. Lists of shadow-banned users are only found
+// by their owner.
+func (r *PostgresRepository) GetListBySlug(slug string, viewerID int) (models.ToiletList, error) {
+	var l models.ToiletList
+	query := `
+        SELECT id, user_id, name, visibility, slug, created_at
+        FROM toilet_lists
+        WHERE slug = $1
+            AND (user_id = $2 OR user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+    `
+	err := r.db.QueryRow(query, slug, viewerID).Scan(&l.ID, &l.UserID, &l.Name, &l.Visibility, &l.Slug, &l.CreatedAt)
+	if err == sql.ErrNoRows {
+		return l, apperr.NotFound("list not found")
+	}
+	if err != nil {
+		return l, err
+	}
+
+	l.Items, err = r.getListItems(l.ID, viewerID)
+	return l, err
+}
+
+// AddListItem saves a toilet into a list owned by userID. A zero position
+// appends the toilet to the end of the list. Only toilets on the map can be
+// saved.
+func (r *PostgresRepository) AddListItem(item models.ListItem, userID int) (models.ListItem, error) {
+	query := `
+        INSERT INTO toilet_list_items (list_id, toilet_id, position, note)
+        SELECT l.id, $2,
+            CASE WHEN $3 > 0 THEN $3
+                 ELSE COALESCE((SELECT MAX(position) FROM toilet_list_items WHERE list_id = l.id), 0) + 1
+            END,
+            $4
+        FROM toilet_lists l
+        JOIN toilets t ON t.id = $2
+        WHERE l.id = $1 AND l.user_id = $5
+            AND t.status = 'visible' AND t.approval = 'approved'
+            AND (t.founder_id = $5 OR t.founder_id NOT IN (SELECT user_id FROM shadow_banned_users))
+        ON CONFLICT (list_id, toilet_id) DO UPDATE SET note = EXCLUDED.note
+        RETURNING position, added_at
+    `
+	err := r.db.QueryRow(query, item.ListID, item.ToiletID, item.Position, item.Note, userID).Scan(&item.Position, &item.AddedAt)
+	if err == sql.ErrNoRows {
+		return models.ListItem{}, apperr.NotFound("not authorized, or list or toilet not found")
+	}
+	if err != nil {
+		return models.ListItem{}, dbError(err)
+	}
+	return item, nil
+}
+
+// UpdateListItem changes the note or position of a saved toilet
+func (r *PostgresRepository) UpdateListItem(item models.ListItem, userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE toilet_list_items i
+        SET position = $3, note = $4
+        FROM toilet_lists l
+        WHERE i.list_id = l.id AND i.list_id = $1 AND i.toilet_id = $2 AND l.user_id = $5
+    `, item.ListID, item.ToiletID, item.Position, item.Note, userID)
+	if err != nil {
+		return err
+	}
+	return checkListAffected(result)
+}
+
+// RemoveListItem removes a toilet from a list
+func (r *PostgresRepository) RemoveListItem(listID, toiletID, userID int) error {
+	result, err := r.db.Exec(`
+        DELETE FROM toilet_list_items i
+        USING toilet_lists l
+        WHERE i.list_id = l.id AND i.list_id = $1 AND i.toilet_id = $2 AND l.user_id = $3
+    `, listID, toiletID, userID)
+	if err != nil {
+		return err
+	}
+	return checkListAffected(result)
+}
+
+// getListItems retrieves the items of a list whose toilets are on the map.
+// Toilets that are hidden or not approved are left out like on the map,
+// and so are those of shadow-banned users unless viewerID is their own.
+func (r *PostgresRepository) getListItems(listID, viewerID int) ([]models.ListItem, error) {
+	query := `
+        SELECT i.list_id, i.toilet_id, i.position, i.note, i.added_at,
+            t.id, t.founder_id, t.name, t.point, t.type, t.gender, t.address
+        FROM toilet_list_items i
+        JOIN toilets t ON t.id = i.toilet_id
+        WHERE i.list_id = $1 AND t.status = 'visible' AND t.approval = 'approved'
+            AND (t.founder_id = $2 OR t.founder_id NOT IN (SELECT user_id FROM shadow_banned_users))
+        ORDER BY i.position, i.added_at
+    `
+	rows, err := r.db.Query(query, listID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ListItem{}
+	for rows.Next() {
+		var item models.ListItem
+		var t models.Toilet
+		if err := rows.Scan(
+			&item.ListID, &item.ToiletID, &item.Position, &item.Note, &item.AddedAt,
+			&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address,
+		); err != nil {
+			return nil, err
+		}
+		item.Toilet = &t
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func checkListAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("not authorized or list not found")
+	}
+	return nil
+}
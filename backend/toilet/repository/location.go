@@ -0,0 +1,12 @@
+package repository
+
+import models "free_toilet_map/toilet/model"
+
+// locationArgs returns the values of the location_check,
+// location_distance and location_accuracy columns, NULLs without a check
+func locationArgs(check *models.LocationCheck) (result, distance, accuracy interface{}) {
+	if check == nil {
+		return nil, nil, nil
+	}
+	return check.Result, check.Distance, check.Accuracy
+}
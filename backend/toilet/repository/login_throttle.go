@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Kinds of login throttle keys
+const (
+	ThrottleAccount = "account"
+	ThrottleIP      = "ip"
+)
+
+// GetLoginLock returns until when logins for a throttle key are locked. The
+// zero time means the key is not locked.
+func (r *PostgresRepository) GetLoginLock(kind, key string) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT locked_until FROM login_throttle WHERE kind = $1 AND key = $2
+    `, kind, key).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordLoginFailure counts a failed login for a throttle key and returns the
+// number of consecutive failures. Failures older than resetBefore are
+// forgotten and counting starts over.
+func (r *PostgresRepository) RecordLoginFailure(kind, key string, now, resetBefore time.Time) (int, error) {
+	var failures int
+	err := r.db.QueryRow(`
+        INSERT INTO login_throttle (kind, key, failures, last_failure_at)
+        VALUES ($1, $2, 1, $3)
+        ON CONFLICT (kind, key) DO UPDATE SET
+            failures = CASE WHEN login_throttle.last_failure_at < $4 THEN 1
+                            ELSE login_throttle.failures + 1 END,
+            last_failure_at = EXCLUDED.last_failure_at
+        RETURNING failures
+    `, kind, key, now, resetBefore).Scan(&failures)
+	return failures, err
+}
+
+// SetLoginLock locks logins for a throttle key until the given time
+func (r *PostgresRepository) SetLoginLock(kind, key string, until time.Time) error {
+	_, err := r.db.Exec(`
+        UPDATE login_throttle SET locked_until = $3 WHERE kind = $1 AND key = $2
+    `, kind, key, until)
+	return err
+}
+
+// ClearLoginFailures forgets the failed logins and any lock of a throttle key
+func (r *PostgresRepository) ClearLoginFailures(kind, key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_throttle WHERE kind = $1 AND key = $2`, kind, key)
+	return err
+}
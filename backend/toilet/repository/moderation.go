@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+)
+
+// UpdateToilet updates a toilet added by userID
+func (r *PostgresRepository) UpdateToilet(toilet models.Toilet, userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE toilets
+        SET name = $1, point = $2, type = $3, gender = $4, address = $5
+        WHERE id = $6 AND founder_id = $7
+    `, toilet.Name, toilet.Point, toilet.Type, toilet.Gender, toilet.Address, toilet.ID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("not authorized or toilet not found"))
+}
+
+// UpdateToiletAsModerator updates any toilet
+func (r *PostgresRepository) UpdateToiletAsModerator(toilet models.Toilet) error {
+	result, err := r.db.Exec(`
+        UPDATE toilets
+        SET name = $1, point = $2, type = $3, gender = $4, address = $5
+        WHERE id = $6
+    `, toilet.Name, toilet.Point, toilet.Type, toilet.Gender, toilet.Address, toilet.ID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("toilet not found"))
+}
+
+// DeleteToiletAsModerator deletes any toilet
+func (r *PostgresRepository) DeleteToiletAsModerator(toiletID int) error {
+	result, err := r.db.Exec(`DELETE FROM toilets WHERE id = $1`, toiletID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("toilet not found"))
+}
+
+// UpdateReview updates a review written by userID
+func (r *PostgresRepository) UpdateReview(review models.Review, userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE reviews
+        SET title = $1, review_text = $2, score = $3
+        WHERE id = $4 AND user_id = $5
+    `, review.Title, review.ReviewText, review.Score, review.ID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("not authorized or review not found"))
+}
+
+// UpdateReviewAsModerator updates any review
+func (r *PostgresRepository) UpdateReviewAsModerator(review models.Review) error {
+	result, err := r.db.Exec(`
+        UPDATE reviews
+        SET title = $1, review_text = $2, score = $3
+        WHERE id = $4
+    `, review.Title, review.ReviewText, review.Score, review.ID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("review not found"))
+}
+
+// DeleteReview deletes a review written by userID
+func (r *PostgresRepository) DeleteReview(reviewID, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM reviews WHERE id = $1 AND user_id = $2`, reviewID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("not authorized or review not found"))
+}
+
+// DeleteReviewAsModerator deletes any review
+func (r *PostgresRepository) DeleteReviewAsModerator(reviewID int) error {
+	result, err := r.db.Exec(`DELETE FROM reviews WHERE id = $1`, reviewID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("review not found"))
+}
+
+// DeleteCommentAsModerator deletes any comment
+func (r *PostgresRepository) DeleteCommentAsModerator(commentID int) error {
+	result, err := r.db.Exec(`DELETE FROM review_comments WHERE id = $1`, commentID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("comment not found"))
+}
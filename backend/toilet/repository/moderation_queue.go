@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrAlreadyReported is returned when a user reports a target that already
+// has an open report of theirs
+var ErrAlreadyReported = apperr.Conflict("you have already reported this")
+
+// targetAuthorQueries look up who is responsible for reported content
+var targetAuthorQueries = map[string]string{
+	models.TargetToilet:  `SELECT founder_id FROM toilets WHERE id = $1`,
+	models.TargetReview:  `SELECT user_id FROM reviews WHERE id = $1`,
+	models.TargetComment: `SELECT user_id FROM review_comments WHERE id = $1`,
+	models.TargetUser:    `SELECT id FROM users WHERE id = $1`,
+}
+
+// GetTargetAuthor returns the user behind a toilet, review or comment, or
+// the user themselves for reported users
+func (r *PostgresRepository) GetTargetAuthor(targetType string, targetID int) (int, error) {
+	query, ok := targetAuthorQueries[targetType]
+	if !ok {
+		return 0, apperr.Invalid("invalid target type")
+	}
+	var userID int
+	err := r.db.QueryRow(query, targetID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, apperr.NotFound(targetType + " not found")
+	}
+	return userID, err
+}
+
+// AddReport files a report, adding it to the open queue item of its target
+// or opening a new one. weight is how much the report counts towards the
+// priority of the item.
+func (r *PostgresRepository) AddReport(report models.Report, weight float64) (models.Report, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        INSERT INTO moderation_items (target_type, target_id)
+        VALUES ($1, $2)
+        ON CONFLICT (target_type, target_id) WHERE state = 'open'
+        DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+        RETURNING id
+    `, report.TargetType, report.TargetID).Scan(&report.ItemID)
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	err = tx.QueryRow(`
+        INSERT INTO reports (item_id, reporter_id, reason, details, weight)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (item_id, reporter_id) DO NOTHING
+        RETURNING id, created_at
+    `, report.ItemID, report.ReporterID, report.Reason, report.Details, weight).Scan(&report.ID, &report.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.Report{}, ErrAlreadyReported
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	return report, tx.Commit()
+}
+
+// moderationItemColumns selects a queue item together with the number of
+// reports, their total weight and their distinct reasons
+const moderationItemColumns = `
+    i.id, i.target_type, i.target_id, i.state, i.assignee_id, i.action,
+    i.resolved_by, i.resolved_at, i.created_at, i.updated_at,
+    (SELECT COUNT(*) FROM reports rp WHERE rp.item_id = i.id),
+    (SELECT COALESCE(SUM(rp.weight), 0) FROM reports rp WHERE rp.item_id = i.id) AS priority,
+    ARRAY(SELECT DISTINCT rp.reason FROM reports rp WHERE rp.item_id = i.id ORDER BY rp.reason)
+`
+
+func scanModerationItem(row rowScanner) (models.ModerationItem, error) {
+	var item models.ModerationItem
+	var assigneeID, resolvedBy sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&item.ID, &item.TargetType, &item.TargetID, &item.State, &assigneeID, &item.Action,
+		&resolvedBy, &resolvedAt, &item.CreatedAt, &item.UpdatedAt,
+		&item.ReportCount, &item.Priority, pq.Array(&item.Reasons),
+	)
+	item.AssigneeID = nullIntPtr(assigneeID)
+	item.ResolvedBy = nullIntPtr(resolvedBy)
+	if resolvedAt.Valid {
+		item.ResolvedAt = &resolvedAt.Time
+	}
+	return item, err
+}
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// ListModerationItems returns the queue items matching a filter, those
+// reported most and by the most trusted users first, then the longest
+// waiting
+func (r *PostgresRepository) ListModerationItems(filter models.ModerationFilter) ([]models.ModerationItem, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.State != "" {
+		where("i.state = $%d", filter.State)
+	}
+	if filter.TargetType != "" {
+		where("i.target_type = $%d", filter.TargetType)
+	}
+	if filter.Reason != "" {
+		where("EXISTS (SELECT 1 FROM reports rp WHERE rp.item_id = i.id AND rp.reason = $%d)", filter.Reason)
+	}
+	if filter.AssigneeID != 0 {
+		where("i.assignee_id = $%d", filter.AssigneeID)
+	}
+	if filter.Unassigned {
+		conditions = append(conditions, "i.assignee_id IS NULL")
+	}
+
+	query := `SELECT ` + moderationItemColumns + ` FROM moderation_items i`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY priority DESC, i.created_at, i.id LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ModerationItem{}
+	for rows.Next() {
+		item, err := scanModerationItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetModerationItem retrieves a queue item with its reports and notes
+func (r *PostgresRepository) GetModerationItem(itemID int) (models.ModerationItem, error) {
+	item, err := scanModerationItem(r.db.QueryRow(`SELECT `+moderationItemColumns+` FROM moderation_items i WHERE i.id = $1`, itemID))
+	if err == sql.ErrNoRows {
+		return item, apperr.NotFound("moderation item not found")
+	}
+	if err != nil {
+		return item, err
+	}
+
+	if item.Reports, err = r.getItemReports(item); err != nil {
+		return item, err
+	}
+	item.Notes, err = r.getItemNotes(itemID)
+	return item, err
+}
+
+func (r *PostgresRepository) getItemReports(item models.ModerationItem) ([]models.Report, error) {
+	rows, err := r.db.Query(`
+        SELECT id, reporter_id, reason, details, created_at
+        FROM reports
+        WHERE item_id = $1
+        ORDER BY created_at, id
+    `, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		rp := models.Report{ItemID: item.ID, TargetType: item.TargetType, TargetID: item.TargetID}
+		var reporterID sql.NullInt64
+		if err := rows.Scan(&rp.ID, &reporterID, &rp.Reason, &rp.Details, &rp.CreatedAt); err != nil {
+			return nil, err
+		}
+		rp.ReporterID = nullIntPtr(reporterID)
+		reports = append(reports, rp)
+	}
+	return reports, rows.Err()
+}
+
+// GetReportsByReporter lists the reports a user filed, for the data export
+func (r *PostgresRepository) GetReportsByReporter(userID int) ([]models.Report, error) {
+	rows, err := r.db.Query(`
+        SELECT rp.id, rp.item_id, i.target_type, i.target_id, rp.reason, rp.details, rp.created_at
+        FROM reports rp
+        JOIN moderation_items i ON i.id = rp.item_id
+        WHERE rp.reporter_id = $1
+        ORDER BY rp.created_at, rp.id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		rp := models.Report{ReporterID: &userID}
+		if err := rows.Scan(&rp.ID, &rp.ItemID, &rp.TargetType, &rp.TargetID, &rp.Reason, &rp.Details, &rp.CreatedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, rp)
+	}
+	return reports, rows.Err()
+}
+
+func (r *PostgresRepository) getItemNotes(itemID int) ([]models.ModerationNote, error) {
+	rows, err := r.db.Query(`
+        SELECT id, author_id, text, created_at
+        FROM moderation_notes
+        WHERE item_id = $1
+        ORDER BY created_at, id
+    `, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.ModerationNote{}
+	for rows.Next() {
+		n := models.ModerationNote{ItemID: itemID}
+		var authorID sql.NullInt64
+		if err := rows.Scan(&n.ID, &authorID, &n.Text, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.AuthorID = nullIntPtr(authorID)
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// AssignModerationItem sets the moderator working on an open item. A nil
+// assignee releases the item.
+func (r *PostgresRepository) AssignModerationItem(itemID int, assigneeID *int) error {
+	result, err := r.db.Exec(`
+        UPDATE moderation_items
+        SET assignee_id = $2, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND state = 'open'
+    `, itemID, assigneeID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.Conflict("moderation item is already resolved"))
+}
+
+// ReopenModerationItem undoes ResolveModerationItem for an action that
+// could not be applied
+func (r *PostgresRepository) ReopenModerationItem(itemID int, now time.Time) error {
+	_, err := r.db.Exec(`
+        UPDATE moderation_items
+        SET state = 'open', action = '', resolved_by = NULL, resolved_at = NULL, updated_at = $2,
+            target_user_id = NULL
+        WHERE id = $1
+    `, itemID, now)
+	return dbError(err)
+}
+
+// AddModerationNote attaches a note to a queue item
+func (r *PostgresRepository) AddModerationNote(note models.ModerationNote) (models.ModerationNote, error) {
+	err := r.db.QueryRow(`
+        INSERT INTO moderation_notes (item_id, author_id, text)
+        SELECT id, $2, $3 FROM moderation_items WHERE id = $1
+        RETURNING id, created_at
+    `, note.ItemID, note.AuthorID, note.Text).Scan(&note.ID, &note.CreatedAt)
+	if err == sql.ErrNoRows {
+		return note, apperr.NotFound("moderation item not found")
+	}
+	return note, dbError(err)
+}
+
+// ResolveModerationItem closes an open item with the action taken.
+// targetUserID is the author of the target the action was taken against,
+// or 0. It fails if the item is no longer open, so that concurrent
+// resolutions cannot both win.
+func (r *PostgresRepository) ResolveModerationItem(itemID int, state, action string, resolvedBy, targetUserID int, now time.Time) error {
+	result, err := r.db.Exec(`
+        UPDATE moderation_items
+        SET state = $2, action = $3, resolved_by = $4, resolved_at = $5, updated_at = $5,
+            target_user_id = NULLIF($6, 0)
+        WHERE id = $1 AND state = 'open'
+    `, itemID, state, action, resolvedBy, now, targetUserID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("moderation item not found or already resolved"))
+}
+
+// SetToiletStatus hides or restores a toilet regardless of its founder
+func (r *PostgresRepository) SetToiletStatus(toiletID int, status string) error {
+	result, err := r.db.Exec(`UPDATE toilets SET status = $1 WHERE id = $2`, status, toiletID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("toilet not found"))
+}
+
+// SetReviewStatus hides or restores a review regardless of its author
+func (r *PostgresRepository) SetReviewStatus(reviewID int, status string) error {
+	result, err := r.db.Exec(`UPDATE reviews SET status = $1 WHERE id = $2`, status, reviewID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("review not found"))
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// ErrOwnerNotFound is returned when a user is not an owner of a toilet
+var ErrOwnerNotFound = apperr.NotFound("owner not found")
+
+// VerifyToiletOwner records that a user runs the venue of a toilet. A
+// user who is already an owner keeps the original verification time.
+func (r *PostgresRepository) VerifyToiletOwner(toiletID, userID int, now time.Time) (models.ToiletOwner, error) {
+	owner := models.ToiletOwner{ToiletID: toiletID, UserID: userID}
+	err := r.db.QueryRow(`
+        INSERT INTO toilet_owners (toilet_id, user_id, verified_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (toilet_id, user_id)
+        DO UPDATE SET verified_at = COALESCE(toilet_owners.verified_at, EXCLUDED.verified_at)
+        RETURNING verified_at
+    `, toiletID, userID, now).Scan(&owner.VerifiedAt)
+	if err != nil {
+		return models.ToiletOwner{}, dbError(err)
+	}
+	return owner, nil
+}
+
+// GetOwnershipsByUser lists the toilets a user was verified to own
+func (r *PostgresRepository) GetOwnershipsByUser(userID int) ([]models.ToiletOwner, error) {
+	rows, err := r.db.Query(`
+        SELECT toilet_id, user_id, verified_at
+        FROM toilet_owners
+        WHERE user_id = $1 AND verified_at IS NOT NULL
+        ORDER BY verified_at, toilet_id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []models.ToiletOwner{}
+	for rows.Next() {
+		var o models.ToiletOwner
+		if err := rows.Scan(&o.ToiletID, &o.UserID, &o.VerifiedAt); err != nil {
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// RevokeToiletOwner removes a user from the owners of a toilet
+func (r *PostgresRepository) RevokeToiletOwner(toiletID, userID int) error {
+	result, err := r.db.Exec(`DELETE FROM toilet_owners WHERE toilet_id = $1 AND user_id = $2`, toiletID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrOwnerNotFound)
+}
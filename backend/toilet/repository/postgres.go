@@ -2,8 +2,7 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
-	"fmt"
+	"free_toilet_map/toilet/apperr"
 	models "free_toilet_map/toilet/model"
 )
 
@@ -17,33 +16,43 @@ func NewPostgresRepoWithDB(db *sql.DB) *PostgresRepository {
 }
 
 // CreateUser creates a new user in the database
-func (r *PostgresRepository) CreateUser(user models.User) (models.User, error) {
-	query := `INSERT INTO users (username, password) VALUES ($1, $2) RETURNING id`
-	err := r.db.QueryRow(query, user.Username, user.Password).Scan(&user.ID)
+func (r *PostgresRepository) CreateUser(user models.User, usernameKey string) (models.User, error) {
+	query := `INSERT INTO users (username, password, email, username_key) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id`
+	err := r.db.QueryRow(query, user.Username, user.Password, user.Email, usernameKey).Scan(&user.ID)
 	if err != nil {
-		if err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"` {
-			return models.User{}, errors.New("username already exists")
-		}
-		return models.User{}, err
+		return models.User{}, dbError(err)
 	}
 	return user, nil
 }
 
 // GetUserByUsername retrieves a user by their username
 func (r *PostgresRepository) GetUserByUsername(username string) (models.User, error) {
-	var user models.User
-	query := `SELECT id, username, password, toilets_found FROM users WHERE username = $1`
-	err := r.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Password, &user.ToiletsFound)
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE users.username = $1`, username))
 	if err == sql.ErrNoRows {
-		return user, errors.New("user not found")
+		return user, ErrUserNotFound
 	}
 	return user, err
 }
 
-// GetAllToilets retrieves all toilets from the database
-func (r *PostgresRepository) GetAllToilets() ([]models.Toilet, error) {
-	query := `SELECT id, founder_id, name, point, type, gender, address FROM toilets`
-	rows, err := r.db.Query(query)
+// GetAllToilets retrieves all visible, approved toilets from the database,
+// and those still pending approval if withPending is set. The rating is the
+// average score of their reviews weighted by the reputation of the
+// reviewers, see Service.AddReview. Reviews flagged as suspicious only
+// count if withFlagged is set. Toilets and reviews of shadow-banned users
+// only count for themselves; viewerID is 0 for anonymous requests.
+func (r *PostgresRepository) GetAllToilets(withPending, withFlagged bool, viewerID int) ([]models.Toilet, error) {
+	query := `
+        SELECT t.id, t.founder_id, t.name, t.point, t.type, t.gender, t.address, t.approval,
+            t.location_check IS NOT DISTINCT FROM 'remote',
+            (SELECT SUM(r.score * r.weight) / NULLIF(SUM(r.weight), 0)
+             FROM reviews r
+             WHERE r.toilet_id = t.id AND r.status = 'visible' AND ($2 OR r.anomaly IS NULL)
+                AND (r.user_id = $3 OR r.user_id NOT IN (SELECT user_id FROM shadow_banned_users)))
+        FROM toilets t
+        WHERE t.status = 'visible' AND (t.approval = 'approved' OR ($1 AND t.approval = 'pending'))
+            AND (t.founder_id = $3 OR t.founder_id NOT IN (SELECT user_id FROM shadow_banned_users))
+    `
+	rows, err := r.db.Query(query, withPending, withFlagged, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -52,9 +61,13 @@ func (r *PostgresRepository) GetAllToilets() ([]models.Toilet, error) {
 	var toilets []models.Toilet
 	for rows.Next() {
 		var t models.Toilet
-		if err := rows.Scan(&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address); err != nil {
+		var rating sql.NullFloat64
+		if err := rows.Scan(&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address, &t.Approval, &t.Remote, &rating); err != nil {
 			return nil, err
 		}
+		if rating.Valid {
+			t.Rating = &rating.Float64
+		}
 		toilets = append(toilets, t)
 	}
 	return toilets, nil
@@ -63,13 +76,16 @@ func (r *PostgresRepository) GetAllToilets() ([]models.Toilet, error) {
 // AddToilet adds a new toilet to the database
 func (r *PostgresRepository) AddToilet(toilet models.Toilet) (models.Toilet, error) {
 	query := `
-        INSERT INTO toilets (founder_id, name, point, type, gender, address)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO toilets (founder_id, name, point, type, gender, address, status, approval,
+            location_check, location_distance, location_accuracy)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         RETURNING id
     `
-	err := r.db.QueryRow(query, toilet.FounderID, toilet.Name, toilet.Point, toilet.Type, toilet.Gender, toilet.Address).Scan(&toilet.ID)
+	check, dist, acc := locationArgs(toilet.LocationCheck)
+	err := r.db.QueryRow(query, toilet.FounderID, toilet.Name, toilet.Point, toilet.Type, toilet.Gender, toilet.Address, toilet.Status, toilet.Approval,
+		check, dist, acc).Scan(&toilet.ID)
 	if err != nil {
-		return models.Toilet{}, err
+		return models.Toilet{}, dbError(err)
 	}
 
 	return toilet, nil
@@ -92,27 +108,34 @@ func (r *PostgresRepository) DeleteToilet(toiletID, userID int) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("not authorized or toilet not found")
+		return apperr.NotFound("not authorized or toilet not found")
 	}
 
 	return nil
 }
 
-func (r *PostgresRepository) AddReview(review models.Review) error {
+// AddReview stores a review. weight is how much it counts towards the
+// rating of the toilet.
+func (r *PostgresRepository) AddReview(review models.Review, weight float64) (models.Review, error) {
 	query := `
-        INSERT INTO reviews (user_id, toilet_id, title, review_text, score) 
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO reviews (user_id, toilet_id, title, review_text, score, status, weight,
+            location_check, location_distance, location_accuracy)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id, created_at
     `
-	_, err := r.db.Exec(query, review.UserID, review.ToiletID, review.Title, review.ReviewText, review.Score)
+	check, dist, acc := locationArgs(review.LocationCheck)
+	err := r.db.QueryRow(query, review.UserID, review.ToiletID, review.Title, review.ReviewText, review.Score, review.Status, weight,
+		check, dist, acc).Scan(&review.ID, &review.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("could not insert review: %w", err)
+		return models.Review{}, dbError(err)
 	}
 
-	return nil
+	return review, nil
 }
 
-// GetReviewsByToilet retrieves all reviews for a specific toilet
-func (r *PostgresRepository) GetReviewsByToilet(toiletID int) ([]models.Review, error) {
+// GetReviewsByToilet retrieves all reviews for a specific toilet. Reviews of
+// shadow-banned users are only shown to themselves.
+func (r *PostgresRepository) GetReviewsByToilet(toiletID, viewerID int) ([]models.Review, error) {
 	query := `
         SELECT 
             reviews.id,
@@ -122,13 +145,15 @@ func (r *PostgresRepository) GetReviewsByToilet(toiletID int) ([]models.Review,
             reviews.review_text,
             reviews.score,
             reviews.created_at,
+            reviews.location_check IS NOT DISTINCT FROM 'remote',
             users.username
         FROM reviews
         JOIN users ON reviews.user_id = users.id
-        WHERE reviews.toilet_id = $1;
+        WHERE reviews.toilet_id = $1 AND reviews.status = 'visible'
+            AND (reviews.user_id = $2 OR reviews.user_id NOT IN (SELECT user_id FROM shadow_banned_users));
     `
 
-	rows, err := r.db.Query(query, toiletID)
+	rows, err := r.db.Query(query, toiletID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +170,7 @@ func (r *PostgresRepository) GetReviewsByToilet(toiletID int) ([]models.Review,
 			&review.ReviewText,
 			&review.Score,
 			&review.CreatedAt,
+			&review.Remote,
 			&review.Username,
 		); err != nil {
 			return nil, err
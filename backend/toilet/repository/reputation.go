@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// GetReputationFactors collects what the reputation of a user is computed
+// from. Reports of the content filter have no reporter and do not count.
+func (r *PostgresRepository) GetReputationFactors(userID int, now time.Time) (models.ReputationFactors, error) {
+	var f models.ReputationFactors
+	var createdAt sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT
+            u.created_at,
+            u.role,
+            (SELECT COUNT(*) FROM toilets WHERE founder_id = u.id AND status = 'visible' AND approval = 'approved'),
+            (SELECT COUNT(*) FROM reviews WHERE user_id = u.id AND status = 'visible'),
+            (SELECT COUNT(*) FROM reports rp JOIN moderation_items i ON i.id = rp.item_id
+             WHERE rp.reporter_id = u.id AND i.state = 'actioned' AND i.action <> 'approve'),
+            (SELECT COUNT(*) FROM reports rp JOIN moderation_items i ON i.id = rp.item_id
+             WHERE rp.reporter_id = u.id AND (i.state = 'dismissed' OR i.action = 'approve')),
+            (SELECT COUNT(*) FROM moderation_items
+             WHERE target_user_id = u.id AND state = 'actioned' AND action IN ('hide', 'delete'))
+            + (SELECT COUNT(*) FROM toilets WHERE founder_id = u.id AND approval = 'rejected'),
+            (SELECT COUNT(*) FROM user_warnings WHERE user_id = u.id),
+            (SELECT COUNT(*) FROM user_bans WHERE user_id = u.id)
+        FROM users u
+        WHERE u.id = $1
+    `, userID).Scan(
+		&createdAt, &f.Role, &f.PublishedToilets, &f.PublishedReviews,
+		&f.ConfirmedReports, &f.DismissedReports, &f.RemovedContent, &f.Warnings, &f.Bans,
+	)
+	if err == sql.ErrNoRows {
+		return f, ErrUserNotFound
+	}
+	if err != nil {
+		return f, err
+	}
+	if createdAt.Valid {
+		f.AccountAgeDays = int(now.Sub(createdAt.Time).Hours() / 24)
+	}
+	return f, nil
+}
+
+// contributionTables maps kinds of contributions to their table and the
+// column holding their author
+var contributionTables = map[string][2]string{
+	"toilet":  {"toilets", "founder_id"},
+	"review":  {"reviews", "user_id"},
+	"comment": {"review_comments", "user_id"},
+	"report":  {"reports", "reporter_id"},
+}
+
+// CountContributionsSince counts the toilets, reviews, comments or reports
+// a user created since the given time
+func (r *PostgresRepository) CountContributionsSince(userID int, kind string, since time.Time) (int, error) {
+	table, ok := contributionTables[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown contribution kind %q", kind)
+	}
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM `+table[0]+` WHERE `+table[1]+` = $1 AND created_at >= $2`,
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+var (
+	// ErrBanNotFound is returned when a ban does not exist or is not in force
+	ErrBanNotFound = apperr.NotFound("ban not found or already lifted")
+	// ErrAlreadyAppealed is returned when a user appeals a ban a second time
+	ErrAlreadyAppealed = apperr.Conflict("you have already appealed this ban")
+	// ErrAppealNotFound is returned when an appeal does not exist or has
+	// already been decided
+	ErrAppealNotFound = apperr.NotFound("appeal not found or already decided")
+)
+
+// AddUserWarning records a warning a moderator gave to a user. itemID may
+// be 0 if the warning is not about a queue item.
+func (r *PostgresRepository) AddUserWarning(userID, itemID, moderatorID int, reason string) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_warnings (user_id, item_id, moderator_id, reason)
+        VALUES ($1, NULLIF($2, 0), $3, $4)
+    `, userID, itemID, moderatorID, reason)
+	return err
+}
+
+// AddUserBan stores a ban. ban.ItemID may be nil if the ban is not about a
+// queue item, and ban.ExpiresAt is nil for bans without end.
+func (r *PostgresRepository) AddUserBan(ban models.Ban) (models.Ban, error) {
+	err := r.db.QueryRow(`
+        INSERT INTO user_bans (user_id, item_id, moderator_id, kind, reason, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, ban.UserID, ban.ItemID, ban.ModeratorID, ban.Kind, ban.Reason, ban.ExpiresAt).Scan(&ban.ID, &ban.CreatedAt)
+	if err != nil {
+		return models.Ban{}, dbError(err)
+	}
+	return ban, nil
+}
+
+const banColumns = `b.id, b.user_id, b.kind, b.reason, b.item_id, b.moderator_id, b.expires_at, b.lifted_at, b.created_at`
+
+func scanBan(row rowScanner) (models.Ban, error) {
+	var b models.Ban
+	var itemID, moderatorID sql.NullInt64
+	var expiresAt, liftedAt sql.NullTime
+	err := row.Scan(&b.ID, &b.UserID, &b.Kind, &b.Reason, &itemID, &moderatorID, &expiresAt, &liftedAt, &b.CreatedAt)
+	b.ItemID = nullIntPtr(itemID)
+	b.ModeratorID = nullIntPtr(moderatorID)
+	b.ExpiresAt = nullTimePtr(expiresAt)
+	b.LiftedAt = nullTimePtr(liftedAt)
+	return b, err
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// GetLockingBan returns the suspension or ban of a user in force at the
+// given time, permanent bans and then the longest first. Shadow bans do not
+// lock the account and are left out. It returns nil if there is none.
+func (r *PostgresRepository) GetLockingBan(userID int, now time.Time) (*models.Ban, error) {
+	ban, err := scanBan(r.db.QueryRow(`
+        SELECT `+banColumns+`
+        FROM user_bans b
+        WHERE b.user_id = $1 AND b.kind IN ('suspension', 'ban') AND b.lifted_at IS NULL
+            AND (b.expires_at IS NULL OR b.expires_at > $2)
+        ORDER BY b.expires_at IS NULL DESC, b.expires_at DESC
+        LIMIT 1
+    `, userID, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// IsShadowBanned reports whether a user is shadow-banned now
+func (r *PostgresRepository) IsShadowBanned(userID int) (bool, error) {
+	var banned bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM shadow_banned_users WHERE user_id = $1)
+    `, userID).Scan(&banned)
+	return banned, err
+}
+
+// GetUserBans lists all bans of a user, the newest first
+func (r *PostgresRepository) GetUserBans(userID int) ([]models.Ban, error) {
+	rows, err := r.db.Query(`
+        SELECT `+banColumns+`
+        FROM user_bans b
+        WHERE b.user_id = $1
+        ORDER BY b.created_at DESC, b.id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := []models.Ban{}
+	for rows.Next() {
+		b, err := scanBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
+
+// GetUserWarnings lists the warnings a user received, the newest first
+func (r *PostgresRepository) GetUserWarnings(userID int) ([]models.Warning, error) {
+	rows, err := r.db.Query(`
+        SELECT id, item_id, moderator_id, reason, created_at
+        FROM user_warnings
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warnings := []models.Warning{}
+	for rows.Next() {
+		var w models.Warning
+		var itemID, moderatorID sql.NullInt64
+		if err := rows.Scan(&w.ID, &itemID, &moderatorID, &w.Reason, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.ItemID = nullIntPtr(itemID)
+		w.ModeratorID = nullIntPtr(moderatorID)
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
+}
+
+// LiftUserBan ends a ban that is still in force
+func (r *PostgresRepository) LiftUserBan(banID, moderatorID int, now time.Time) error {
+	result, err := r.db.Exec(`
+        UPDATE user_bans SET lifted_at = $3, lifted_by = $2
+        WHERE id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $3)
+    `, banID, moderatorID, now)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrBanNotFound)
+}
+
+// AddAppeal stores the appeal of a ban. A ban can be appealed once.
+func (r *PostgresRepository) AddAppeal(appeal models.Appeal) (models.Appeal, error) {
+	err := r.db.QueryRow(`
+        INSERT INTO ban_appeals (ban_id, user_id, text)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (ban_id) DO NOTHING
+        RETURNING id, state, created_at
+    `, appeal.BanID, appeal.UserID, appeal.Text).Scan(&appeal.ID, &appeal.State, &appeal.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.Appeal{}, ErrAlreadyAppealed
+	}
+	if err != nil {
+		return models.Appeal{}, err
+	}
+	return appeal, nil
+}
+
+const appealColumns = `a.id, a.ban_id, a.user_id, a.text, a.state, a.response, a.decided_by, a.decided_at, a.created_at, ` + banColumns
+
+func scanAppeal(row rowScanner) (models.Appeal, error) {
+	var a models.Appeal
+	var b models.Ban
+	var decidedBy, itemID, moderatorID sql.NullInt64
+	var decidedAt, expiresAt, liftedAt sql.NullTime
+	err := row.Scan(
+		&a.ID, &a.BanID, &a.UserID, &a.Text, &a.State, &a.Response, &decidedBy, &decidedAt, &a.CreatedAt,
+		&b.ID, &b.UserID, &b.Kind, &b.Reason, &itemID, &moderatorID, &expiresAt, &liftedAt, &b.CreatedAt,
+	)
+	a.DecidedBy = nullIntPtr(decidedBy)
+	a.DecidedAt = nullTimePtr(decidedAt)
+	b.ItemID = nullIntPtr(itemID)
+	b.ModeratorID = nullIntPtr(moderatorID)
+	b.ExpiresAt = nullTimePtr(expiresAt)
+	b.LiftedAt = nullTimePtr(liftedAt)
+	a.Ban = &b
+	return a, err
+}
+
+// GetAppeal retrieves an appeal together with its ban
+func (r *PostgresRepository) GetAppeal(appealID int) (models.Appeal, error) {
+	appeal, err := scanAppeal(r.db.QueryRow(`
+        SELECT `+appealColumns+`
+        FROM ban_appeals a JOIN user_bans b ON b.id = a.ban_id
+        WHERE a.id = $1
+    `, appealID))
+	if err == sql.ErrNoRows {
+		return models.Appeal{}, ErrAppealNotFound
+	}
+	return appeal, err
+}
+
+// GetOpenAppeals lists the appeals waiting for a decision, oldest first
+func (r *PostgresRepository) GetOpenAppeals(limit, offset int) ([]models.Appeal, error) {
+	rows, err := r.db.Query(`
+        SELECT `+appealColumns+`
+        FROM ban_appeals a JOIN user_bans b ON b.id = a.ban_id
+        WHERE a.state = 'open'
+        ORDER BY a.created_at, a.id
+        LIMIT $1 OFFSET $2
+    `, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appeals := []models.Appeal{}
+	for rows.Next() {
+		a, err := scanAppeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		appeals = append(appeals, a)
+	}
+	return appeals, rows.Err()
+}
+
+// GetAppealsByUser lists the appeals of a user with their bans, the newest
+// first
+func (r *PostgresRepository) GetAppealsByUser(userID int) ([]models.Appeal, error) {
+	rows, err := r.db.Query(`
+        SELECT `+appealColumns+`
+        FROM ban_appeals a JOIN user_bans b ON b.id = a.ban_id
+        WHERE a.user_id = $1
+        ORDER BY a.created_at DESC, a.id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appeals := []models.Appeal{}
+	for rows.Next() {
+		a, err := scanAppeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		appeals = append(appeals, a)
+	}
+	return appeals, rows.Err()
+}
+
+// DecideAppeal accepts or rejects an open appeal. Accepting lifts the ban
+// if it is still in force.
+func (r *PostgresRepository) DecideAppeal(appealID int, state, response string, moderatorID int, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var banID int
+	err = tx.QueryRow(`
+        UPDATE ban_appeals SET state = $2, response = $3, decided_by = $4, decided_at = $5
+        WHERE id = $1 AND state = 'open'
+        RETURNING ban_id
+    `, appealID, state, response, moderatorID, now).Scan(&banID)
+	if err == sql.ErrNoRows {
+		return ErrAppealNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if state == models.AppealAccepted {
+		if _, err := tx.Exec(`
+            UPDATE user_bans SET lifted_at = $3, lifted_by = $2
+            WHERE id = $1 AND lifted_at IS NULL
+        `, banID, moderatorID, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
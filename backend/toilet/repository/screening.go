@@ -0,0 +1,30 @@
+package repository
+
+import "time"
+
+// GetRecentReviewTexts returns the title and text of the reviews a user
+// wrote since the given time, newest first, for duplicate detection. The
+// review excludeID is left out so that it is not a duplicate of itself.
+func (r *PostgresRepository) GetRecentReviewTexts(userID, excludeID int, since time.Time, limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+        SELECT COALESCE(title, '') || E'\n' || COALESCE(review_text, '')
+        FROM reviews
+        WHERE user_id = $1 AND id <> $2 AND created_at >= $3
+        ORDER BY created_at DESC
+        LIMIT $4
+    `, userID, excludeID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
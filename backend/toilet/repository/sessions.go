@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = apperr.Unauthorized("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The whole session is revoked when this happens.
+	ErrRefreshTokenReused = apperr.Unauthorized("refresh token reuse detected")
+)
+
+// CreateSession starts a new session for a user with its first refresh token.
+// The user agent and IP describe the device the user signed in from.
+func (r *PostgresRepository) CreateSession(userID int, sessionID, tokenHash string, expiresAt time.Time, userAgent, ip string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        INSERT INTO sessions (id, user_id, user_agent, ip, last_seen_at)
+        VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+    `, sessionID, userID, userAgent, ip); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+        VALUES ($1, $2, $3)
+    `, tokenHash, sessionID, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshToken marks the refresh token identified by oldHash as used and
+// stores newHash as its successor in the same session. It returns the owner
+// of the session. Presenting a token that was already used revokes the
+// session and returns ErrRefreshTokenReused.
+func (r *PostgresRepository) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (int, string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID    int
+		sessionID string
+		tokenExp  time.Time
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+	err = tx.QueryRow(`
+        SELECT s.user_id, s.id, rt.expires_at, rt.used_at, s.revoked_at
+        FROM refresh_tokens rt
+        JOIN sessions s ON s.id = rt.session_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE
+    `, oldHash).Scan(&userID, &sessionID, &tokenExp, &usedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return 0, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if revokedAt.Valid {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	if usedAt.Valid {
+		if _, err := tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1`, sessionID); err != nil {
+			return 0, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, "", err
+		}
+		return 0, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(tokenExp) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_hash = $1`, oldHash); err != nil {
+		return 0, "", err
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+        VALUES ($1, $2, $3)
+    `, newHash, sessionID, expiresAt); err != nil {
+		return 0, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", err
+	}
+	return userID, sessionID, nil
+}
+
+// RevokeSession revokes a single session of a user
+func (r *PostgresRepository) RevokeSession(sessionID string, userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user
+func (r *PostgresRepository) RevokeUserSessions(userID int) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID)
+	return err
+}
+
+// IsSessionActive reports whether a session exists, belongs to the user and
+// has not been revoked
+func (r *PostgresRepository) IsSessionActive(sessionID string, userID int) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM sessions
+            WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+        )
+    `, sessionID, userID).Scan(&active)
+	return active, err
+}
+
+// GetActiveSessions lists the sessions of a user that are neither revoked
+// nor expired, most recently used first
+func (r *PostgresRepository) GetActiveSessions(userID int, now time.Time) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+        SELECT s.id, s.user_agent, s.ip, s.created_at, COALESCE(s.last_seen_at, s.created_at)
+        FROM sessions s
+        WHERE s.user_id = $1 AND s.revoked_at IS NULL
+          AND EXISTS (
+              SELECT 1 FROM refresh_tokens rt
+              WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expires_at > $2
+          )
+        ORDER BY 5 DESC
+    `, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSessionsByUser lists all sessions of a user, including revoked and
+// expired ones, for the data export
+func (r *PostgresRepository) GetSessionsByUser(userID int) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+        SELECT s.id, s.user_agent, s.ip, s.created_at, COALESCE(s.last_seen_at, s.created_at), s.revoked_at
+        FROM sessions s
+        WHERE s.user_id = $1
+        ORDER BY s.created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		s.RevokedAt = nullTimePtr(revokedAt)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession records that a session was used. The row is only written if
+// the last recorded use is older than the given time, so that busy clients
+// do not cause a write per request.
+func (r *PostgresRepository) TouchSession(sessionID string, now, staleBefore time.Time) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions SET last_seen_at = $2
+        WHERE id = $1 AND (last_seen_at IS NULL OR last_seen_at < $3)
+    `, sessionID, now, staleBefore)
+	return err
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	"time"
+)
+
+// ErrInvalidLoginChallenge is returned for unknown or expired login
+// challenges and for challenges that ran out of attempts
+var ErrInvalidLoginChallenge = apperr.Unauthorized("invalid or expired login challenge")
+
+// SaveTOTPSecret stores the secret of a new enrollment, replacing any
+// unfinished one. It fails if the user already has 2FA enabled.
+func (r *PostgresRepository) SaveTOTPSecret(userID int, secret string) error {
+	result, err := r.db.Exec(`
+        INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP
+        WHERE user_totp.confirmed_at IS NULL
+    `, userID, secret)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.Conflict("two-factor authentication is already enabled"))
+}
+
+// GetTOTPSecret returns the user's TOTP secret, whether enrollment was
+// confirmed and the last time step a code was accepted for. The secret is
+// empty if the user never started enrolling.
+func (r *PostgresRepository) GetTOTPSecret(userID int) (string, bool, int64, error) {
+	var (
+		secret    string
+		confirmed bool
+		lastStep  int64
+	)
+	err := r.db.QueryRow(`
+        SELECT secret, confirmed_at IS NOT NULL, last_used_step FROM user_totp WHERE user_id = $1
+    `, userID).Scan(&secret, &confirmed, &lastStep)
+	if err == sql.ErrNoRows {
+		return "", false, 0, nil
+	}
+	return secret, confirmed, lastStep, err
+}
+
+// IsTOTPEnabled reports whether the user has confirmed 2FA
+func (r *PostgresRepository) IsTOTPEnabled(userID int) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL)
+    `, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// ConfirmTOTP finishes enrollment with the step of the first valid code and
+// stores the user's recovery codes
+func (r *PostgresRepository) ConfirmTOTP(userID int, step int64, codeHashes []string, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE user_totp SET confirmed_at = $3, last_used_step = $2
+        WHERE user_id = $1 AND confirmed_at IS NULL
+    `, userID, step, now)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(result, apperr.Conflict("no two-factor enrollment in progress")); err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records that a code of the given step was accepted. It returns
+// false if a code of this or a later step was already used.
+func (r *PostgresRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+        UPDATE user_totp SET last_used_step = $2
+        WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+    `, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected == 1, err
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used. It
+// returns false if there is no such code.
+func (r *PostgresRepository) UseRecoveryCode(userID int, codeHash string, now time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+        UPDATE totp_recovery_codes SET used_at = $3
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `, userID, codeHash, now)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected == 1, err
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores new ones
+func (r *PostgresRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`
+            INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+        `, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *PostgresRepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+        SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+    `, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteTOTP turns off 2FA for a user and removes their recovery codes
+func (r *PostgresRepository) DeleteTOTP(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateLoginChallenge stores a login that waits for the second factor
+func (r *PostgresRepository) CreateLoginChallenge(tokenHash string, userID int, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT INTO login_challenges (token_hash, user_id, expires_at) VALUES ($1, $2, $3)
+    `, tokenHash, userID, expiresAt)
+	return err
+}
+
+// AttemptLoginChallenge counts an attempt to complete a login challenge and
+// returns the user it belongs to. Expired challenges and challenges that
+// ran out of attempts are rejected.
+func (r *PostgresRepository) AttemptLoginChallenge(tokenHash string, maxAttempts int, now time.Time) (int, error) {
+	var userID int
+	err := r.db.QueryRow(`
+        UPDATE login_challenges SET attempts = attempts + 1
+        WHERE token_hash = $1 AND expires_at > $2 AND attempts < $3
+        RETURNING user_id
+    `, tokenHash, now, maxAttempts).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidLoginChallenge
+	}
+	return userID, err
+}
+
+// DeleteLoginChallenge removes a completed challenge together with any
+// expired ones
+func (r *PostgresRepository) DeleteLoginChallenge(tokenHash string, now time.Time) error {
+	_, err := r.db.Exec(`
+        DELETE FROM login_challenges WHERE token_hash = $1 OR expires_at < $2
+    `, tokenHash, now)
+	return err
+}
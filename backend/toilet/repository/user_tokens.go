@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"time"
+)
+
+// Purposes of the single-use tokens sent by email, or handed out to banned
+// users when they sign in
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+	TokenBanAppeal         = "ban_appeal"
+	TokenReauthentication  = "reauthentication"
+)
+
+// GetUserByEmail retrieves a user by their email address, ignoring case
+func (r *PostgresRepository) GetUserByEmail(email string) (models.User, error) {
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE LOWER(users.email) = LOWER($1)`, email))
+	if err == sql.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// CreateUserToken stores the hash of a single-use token
+func (r *PostgresRepository) CreateUserToken(userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at)
+        VALUES ($1, $2, $3, $4)
+    `, tokenHash, userID, purpose, expiresAt)
+	return err
+}
+
+// ConsumeUserToken marks a token as used and returns its user. Unknown,
+// expired and already used tokens are rejected.
+func (r *PostgresRepository) ConsumeUserToken(purpose, tokenHash string, now time.Time) (int, error) {
+	var userID int
+	err := r.db.QueryRow(`
+        UPDATE user_tokens SET used_at = $3
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+        RETURNING user_id
+    `, tokenHash, purpose, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, apperr.Invalid("invalid or expired token")
+	}
+	return userID, err
+}
+
+// GetUserTokenUser returns the user of a token that could be consumed,
+// without using it up
+func (r *PostgresRepository) GetUserTokenUser(purpose, tokenHash string, now time.Time) (int, error) {
+	var userID int
+	err := r.db.QueryRow(`
+        SELECT user_id FROM user_tokens
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+    `, tokenHash, purpose, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, apperr.Invalid("invalid or expired token")
+	}
+	return userID, err
+}
+
+// InvalidateUserTokens marks all unused tokens of a user for a purpose as used
+func (r *PostgresRepository) InvalidateUserTokens(userID int, purpose string) error {
+	_, err := r.db.Exec(`
+        UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+    `, userID, purpose)
+	return err
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *PostgresRepository) UpdatePassword(userID int, passwordHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET password = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("user not found"))
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (r *PostgresRepository) MarkEmailVerified(userID int) error {
+	result, err := r.db.Exec(`
+        UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+        WHERE id = $1
+    `, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, apperr.NotFound("user not found"))
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"database/sql"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+)
+
+// ErrUserNotFound is returned when a user lookup finds no account
+var ErrUserNotFound = apperr.NotFound("user not found")
+
+// userColumns lists the columns scanned by scanUser. toilets_found is
+// counted on the fly so that it always matches the toilets table.
+const userColumns = `
+    users.id, users.username, users.password,
+    (SELECT COUNT(*) FROM toilets WHERE toilets.founder_id = users.id),
+    users.display_name, users.avatar_url, users.bio, users.preferred_language, users.role, users.created_at,
+    COALESCE(users.email, ''), users.email_verified_at IS NOT NULL
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (models.User, error) {
+	var u models.User
+	err := row.Scan(
+		&u.ID, &u.Username, &u.Password,
+		&u.ToiletsFound,
+		&u.DisplayName, &u.AvatarURL, &u.Bio, &u.PreferredLanguage, &u.Role, &u.CreatedAt,
+		&u.Email, &u.EmailVerified,
+	)
+	return u, err
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *PostgresRepository) GetUserByID(userID int) (models.User, error) {
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE users.id = $1`, userID))
+	if err == sql.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// UpdateUserProfile applies the non-nil fields of update to the user's profile
+func (r *PostgresRepository) UpdateUserProfile(userID int, update models.ProfileUpdate) error {
+	result, err := r.db.Exec(`
+        UPDATE users SET
+            display_name = COALESCE($2, display_name),
+            avatar_url = COALESCE($3, avatar_url),
+            bio = COALESCE($4, bio),
+            preferred_language = COALESCE($5, preferred_language)
+        WHERE id = $1
+    `, userID, update.DisplayName, update.AvatarURL, update.Bio, update.PreferredLanguage)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetUserRole changes the role of a user
+func (r *PostgresRepository) SetUserRole(userID int, role string) error {
+	result, err := r.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CountReviewsByUser returns the number of reviews written by a user
+func (r *PostgresRepository) CountReviewsByUser(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM reviews WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetToiletsByFounder retrieves all toilets added by a user
+func (r *PostgresRepository) GetToiletsByFounder(userID int) ([]models.Toilet, error) {
+	query := `
+        SELECT id, founder_id, name, point, type, gender, address, status, approval, approval_reason
+        FROM toilets
+        WHERE founder_id = $1
+        ORDER BY id DESC
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	toilets := []models.Toilet{}
+	for rows.Next() {
+		var t models.Toilet
+		if err := rows.Scan(&t.ID, &t.FounderID, &t.Name, &t.Point, &t.Type, &t.Gender, &t.Address, &t.Status, &t.Approval, &t.ApprovalReason); err != nil {
+			return nil, err
+		}
+		toilets = append(toilets, t)
+	}
+	return toilets, rows.Err()
+}
+
+// GetReviewsByUser retrieves all reviews written by a user, newest first
+func (r *PostgresRepository) GetReviewsByUser(userID int) ([]models.Review, error) {
+	query := `
+        SELECT 
+            reviews.id,
+            reviews.user_id,
+            reviews.toilet_id,
+            reviews.title,
+            reviews.review_text,
+            reviews.score,
+            reviews.created_at,
+            users.username,
+            reviews.status
+        FROM reviews
+        JOIN users ON reviews.user_id = users.id
+        WHERE reviews.user_id = $1
+        ORDER BY reviews.created_at DESC;
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var review models.Review
+		if err := rows.Scan(
+			&review.ID,
+			&review.UserID,
+			&review.ToiletID,
+			&review.Title,
+			&review.ReviewText,
+			&review.Score,
+			&review.CreatedAt,
+			&review.Username,
+			&review.Status,
+		); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// GetPublicListsByUser retrieves the public lists of a user, without items
+func (r *PostgresRepository) GetPublicListsByUser(userID int) ([]models.ToiletList, error) {
+	query := `
+        SELECT id, user_id, name, visibility, slug, created_at
+        FROM toilet_lists
+        WHERE user_id = $1 AND visibility = 'public'
+        ORDER BY created_at, id
+    `
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.ToiletList{}
+	for rows.Next() {
+		var l models.ToiletList
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &l.Visibility, &l.Slug, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
@@ -0,0 +1,194 @@
+package screening
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Wordlist describes the obscene words of one language. Words are matched
+// by their roots so that one entry covers all inflected and derived forms.
+// All entries are lowercase and use the script of the language.
+type Wordlist struct {
+	// Latin is set for languages written in the Latin script. Text is
+	// matched after undoing lookalike and leetspeak substitutions towards
+	// the script of the list.
+	Latin bool
+	// Roots match at the start of a word, either directly or after one of
+	// Prefixes: "еба" covers "ебать" as well as "заебал" and "выебон".
+	Roots    []string
+	Prefixes []string
+	// Anywhere match inside a word, for roots that appear in compounds
+	Anywhere []string
+	// Words match only whole words
+	Words []string
+	// Exceptions are harmless words that would otherwise match
+	Exceptions []string
+}
+
+// RussianProfanity covers Russian mat and the most common slurs
+var RussianProfanity = Wordlist{
+	Roots: []string{
+		"хуй", "хуе", "хуя", "хую", "хуи",
+		"еба", "ебл", "ебу", "ебн", "ебо", "еби", "ебет", "ебуч",
+		"бляд", "блят",
+		"мудак", "мудач", "мудил", "мудозвон",
+		"пидор", "пидар", "пидр",
+		"залуп", "гандон", "гондон", "шлюх",
+	},
+	Prefixes: []string{
+		"за", "от", "по", "на", "вы", "у", "до", "раз", "рас", "съ", "въ", "при",
+		"про", "об", "о", "под", "из", "пере", "недо", "вз", "долбо", "ах",
+	},
+	Anywhere: []string{"пизд"},
+	Words: []string{
+		"бля", "блять", "блядь",
+		"сука", "суки", "суку", "сукой", "суке", "сучка", "сучки", "сучара",
+		"манда", "хули", "педик", "педика", "педики", "педиков",
+	},
+}
+
+// EnglishProfanity covers English obscenities and the most common slurs
+var EnglishProfanity = Wordlist{
+	Latin: true,
+	Roots: []string{
+		"cunt", "bitch", "whore", "slut", "fag", "nigg", "bastard", "asshole", "wank", "twat",
+	},
+	Prefixes:   []string{"dumb", "mother", "dip", "horse", "bull"},
+	Anywhere:   []string{"fuck", "shit", "motherf"},
+	Words:      []string{"fck", "fuk", "dickhead", "cocksucker"},
+	Exceptions: []string{"shiitake", "shitake", "fagot", "niggle", "niggles", "niggling"},
+}
+
+// toCyrillic undoes Latin lookalikes and digits used in place of Cyrillic
+// letters, as in "xyй" or "cyka"
+var toCyrillic = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'i': 'и', 'k': 'к', 'm': 'м',
+	'n': 'п', 'o': 'о', 'p': 'р', 't': 'т', 'u': 'и', 'x': 'х', 'y': 'у',
+	'0': 'о', '3': 'з', '4': 'ч', '6': 'б', '@': 'а',
+}
+
+// toLatin undoes leetspeak and Cyrillic lookalikes, as in "sh1t" or "fаck"
+// with a Cyrillic "а"
+var toLatin = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's', '!': 'i',
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+}
+
+// Profanity rejects content with obscene words from any of its lists
+type Profanity struct {
+	Lists []Wordlist
+}
+
+// Check implements Checker
+func (p Profanity) Check(c Content) (Result, error) {
+	for _, word := range words(c.Text()) {
+		for _, list := range p.Lists {
+			if list.matches(word) {
+				return Result{Decision: Reject, Reason: "profanity"}, nil
+			}
+		}
+	}
+	return Result{}, nil
+}
+
+func (l Wordlist) matches(word string) bool {
+	table := toCyrillic
+	if l.Latin {
+		table = toLatin
+	}
+	w := squeeze(mapRunes(word, table))
+
+	for _, e := range l.Exceptions {
+		if w == e {
+			return false
+		}
+	}
+	for _, v := range l.Words {
+		if w == v {
+			return true
+		}
+	}
+	for _, v := range l.Anywhere {
+		if strings.Contains(w, v) {
+			return true
+		}
+	}
+	for _, root := range l.Roots {
+		if strings.HasPrefix(w, root) {
+			return true
+		}
+		for _, prefix := range l.Prefixes {
+			if strings.HasPrefix(w, prefix) && strings.HasPrefix(w[len(prefix):], root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// words splits text into lowercase words. Characters used as letter
+// substitutes stay part of a word, and words spelled out letter by letter
+// ("х у й", "f.u.c.k") are joined back together.
+func words(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("@$!", r)
+	})
+
+	var result []string
+	var spelled []string
+	flush := func() {
+		if len(spelled) >= 3 {
+			result = append(result, strings.Join(spelled, ""))
+		} else {
+			result = append(result, spelled...)
+		}
+		spelled = spelled[:0]
+	}
+	for _, f := range fields {
+		if strings.IndexFunc(f, unicode.IsLetter) < 0 {
+			flush()
+			continue
+		}
+		if len([]rune(f)) == 1 {
+			spelled = append(spelled, f)
+			continue
+		}
+		flush()
+		result = append(result, f)
+	}
+	flush()
+	return result
+}
+
+func mapRunes(s string, table map[rune]rune) string {
+	return strings.Map(func(r rune) rune {
+		if m, ok := table[r]; ok {
+			return m
+		}
+		return r
+	}, s)
+}
+
+// squeeze shortens runs of three or more equal characters to one, so that
+// "fuuuuck" matches "fuck". Runs of two are kept since they are part of
+// many ordinary spellings.
+func squeeze(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		if j-i >= 3 {
+			b.WriteRune(runes[i])
+		} else {
+			b.WriteString(string(runes[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,159 @@
+// Package screening checks user-submitted text for spam and abuse before
+// it is published. A Pipeline runs a chain of Checkers over the text and
+// the strictest decision wins: content is published, held back for a
+// moderator, or rejected with the reasons so the author can fix it.
+package screening
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Decision is what should happen with screened content. Decisions are
+// ordered from the most to the least permissive.
+type Decision int
+
+const (
+	Allow Decision = iota
+	Hold
+	Reject
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+// Kinds of content that are screened
+const (
+	KindToilet  = "toilet"
+	KindReview  = "review"
+	KindComment = "comment"
+)
+
+// Content is a piece of user-submitted text. Fields holds the separate
+// text fields, e.g. the title and the text of a review.
+type Content struct {
+	Kind   string
+	ID     int // of the edited toilet or review, 0 for new content
+	UserID int
+	Fields []string
+}
+
+// Text joins all fields of the content
+func (c Content) Text() string {
+	return strings.Join(c.Fields, "\n")
+}
+
+// Result is the outcome of a single checker. Reason is a short code such
+// as "profanity" and is empty for Allow.
+type Result struct {
+	Decision Decision
+	Reason   string
+}
+
+// Checker inspects content
+type Checker interface {
+	Check(c Content) (Result, error)
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(c Content) (Result, error)
+
+// Check calls f(c)
+func (f CheckerFunc) Check(c Content) (Result, error) {
+	return f(c)
+}
+
+// Verdict combines the results of all checkers of a pipeline
+type Verdict struct {
+	Decision Decision
+	Reasons  []string // reasons of all checkers that did not allow the content
+}
+
+// Pipeline is a chain of checkers. A nil pipeline allows everything.
+type Pipeline []Checker
+
+// Screen runs all checkers and returns the strictest decision. All
+// checkers run, so that a rejected author learns about every problem at
+// once.
+func (p Pipeline) Screen(c Content) (Verdict, error) {
+	var v Verdict
+	for _, checker := range p {
+		res, err := checker.Check(c)
+		if err != nil {
+			return Verdict{}, err
+		}
+		if res.Decision == Allow {
+			continue
+		}
+		if res.Decision > v.Decision {
+			v.Decision = res.Decision
+		}
+		v.Reasons = appendUnique(v.Reasons, res.Reason)
+	}
+	return v, nil
+}
+
+// Err returns a Rejected error for rejected content and nil otherwise
+func (v Verdict) Err() error {
+	if v.Decision != Reject {
+		return nil
+	}
+	return Rejected{Reasons: v.Reasons}
+}
+
+// Rejected is returned for content that must not be published. It is
+// encoded as 422 with the reasons, so the client can tell the author what
+// to change.
+type Rejected struct {
+	Reasons []string
+}
+
+func (e Rejected) Error() string {
+	return "content rejected: " + strings.Join(e.Reasons, ", ")
+}
+
+// StatusCode makes the transport answer with 422 Unprocessable Entity
+func (e Rejected) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// MarshalJSON adds the reasons to the error body
+func (e Rejected) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error   string   `json:"error"`
+		Reasons []string `json:"reasons"`
+	}{"content rejected", e.Reasons})
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// NewDefaultPipeline returns the checkers used in production. history is
+// used to find reviews the author already posted; it may be nil.
+func NewDefaultPipeline(history HistoryFunc) Pipeline {
+	p := Pipeline{
+		Profanity{Lists: []Wordlist{RussianProfanity, EnglishProfanity}},
+		Links{},
+		PhoneNumbers{},
+		RepeatedText{},
+		Caps{},
+	}
+	if history != nil {
+		p = append(p, Duplicates{History: history})
+	}
+	return p
+}
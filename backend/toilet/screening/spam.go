@@ -0,0 +1,157 @@
+package screening
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)(https?://|www\.|\b[a-z0-9-]+\.(ru|com|net|org|su|рф|info|biz|io|me|xyz|top|online|site|club|shop|pro)\b|\bt\.me/|[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,})`)
+	// phonePattern finds runs of 10 to 15 digits with the separators used in
+	// phone numbers. Addresses and opening hours have fewer digits in a row.
+	phonePattern = regexp.MustCompile(`\+?\d(?:[\s\-().]{0,2}\d){9,14}`)
+)
+
+// Links holds content with web addresses or email addresses. They are
+// rarely needed in a review and are the usual payload of spam.
+type Links struct{}
+
+// Check implements Checker
+func (Links) Check(c Content) (Result, error) {
+	if linkPattern.MatchString(c.Text()) {
+		return Result{Decision: Hold, Reason: "link"}, nil
+	}
+	return Result{}, nil
+}
+
+// PhoneNumbers holds content with phone numbers
+type PhoneNumbers struct{}
+
+// Check implements Checker
+func (PhoneNumbers) Check(c Content) (Result, error) {
+	if phonePattern.MatchString(c.Text()) {
+		return Result{Decision: Hold, Reason: "phone_number"}, nil
+	}
+	return Result{}, nil
+}
+
+// RepeatedText holds content that is padded with repetitions: a character
+// repeated many times in a row or a few words repeated over and over
+type RepeatedText struct{}
+
+const (
+	maxRepeatedChars    = 10
+	minWordsForRatio    = 8
+	minUniqueWordsRatio = 0.3
+)
+
+// Check implements Checker
+func (RepeatedText) Check(c Content) (Result, error) {
+	for _, field := range c.Fields {
+		if longestRun(field) >= maxRepeatedChars {
+			return Result{Decision: Hold, Reason: "repeated_text"}, nil
+		}
+
+		fields := strings.Fields(strings.ToLower(field))
+		if len(fields) < minWordsForRatio {
+			continue
+		}
+		unique := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			unique[f] = true
+		}
+		if float64(len(unique))/float64(len(fields)) < minUniqueWordsRatio {
+			return Result{Decision: Hold, Reason: "repeated_text"}, nil
+		}
+	}
+	return Result{}, nil
+}
+
+// longestRun returns the length of the longest run of one non-space
+// character
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range s {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// Caps holds content that is written mostly in capital letters. Short
+// texts are not checked; a title like "ЧИСТО" is fine.
+type Caps struct{}
+
+const (
+	minLettersForCaps = 20
+	maxUpperRatio     = 0.7
+)
+
+// Check implements Checker
+func (Caps) Check(c Content) (Result, error) {
+	letters, upper := 0, 0
+	for _, r := range c.Text() {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	if letters >= minLettersForCaps && float64(upper)/float64(letters) > maxUpperRatio {
+		return Result{Decision: Hold, Reason: "caps"}, nil
+	}
+	return Result{}, nil
+}
+
+// HistoryFunc returns texts the author of c recently posted of the same
+// kind, see Content.Text
+type HistoryFunc func(c Content) ([]string, error)
+
+// Duplicates rejects reviews that repeat a text their author already
+// posted, which is how review spam usually looks. Toilets are not checked
+// since many are legitimately called the same.
+type Duplicates struct {
+	History HistoryFunc
+}
+
+// minDuplicateLength keeps short reviews like "Чисто" from counting as
+// duplicates
+const minDuplicateLength = 20
+
+// Check implements Checker
+func (d Duplicates) Check(c Content) (Result, error) {
+	if c.Kind != KindReview {
+		return Result{}, nil
+	}
+	text := normalizeSpace(c.Text())
+	if utf8.RuneCountInString(text) < minDuplicateLength {
+		return Result{}, nil
+	}
+
+	previous, err := d.History(c)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, p := range previous {
+		if normalizeSpace(p) == text {
+			return Result{Decision: Reject, Reason: "duplicate"}, nil
+		}
+	}
+	return Result{}, nil
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/mail"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	netmail "net/mail"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// ForgotPassword sends a password reset link to the user identified by
+// username or email. It reports success whether or not such a user exists,
+// so it cannot be used to find out which accounts are registered.
+func (s *Service) ForgotPassword(login string) error {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return apperr.Invalid("username or email is required")
+	}
+
+	var (
+		user models.User
+		err  error
+	)
+	if strings.Contains(login, "@") {
+		user, err = s.Repo.GetUserByEmail(login)
+	} else {
+		user, err = s.Repo.GetUserByUsername(login)
+	}
+	if err != nil || user.Email == "" {
+		return nil
+	}
+
+	token, err := s.issueUserToken(user.ID, repository.TokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		log.Printf("could not create password reset token for user %d: %v", user.ID, err)
+		return nil
+	}
+
+	s.sendAsync(mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля — Free Toilet Map",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+			"Чтобы задать новый пароль, перейдите по ссылке (действует 1 час):\n%s\n\n"+
+			"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Username, s.link("/reset-password", token)),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password using a token from a reset email.
+// All sessions of the user are revoked and other reset links stop working.
+// The reset is recorded in the audit log. A password the policy refuses
+// leaves the link usable for another try.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	userID, err := s.Repo.GetUserTokenUser(repository.TokenPasswordReset, hashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Passwords.Check(password, user.Username).Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.Repo.ConsumeUserToken(repository.TokenPasswordReset, hashToken(token), time.Now().UTC()); err != nil {
+		return err
+	}
+	before, err := s.AuditSnapshot(models.TargetUser, strconv.Itoa(userID))
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	if err := s.Repo.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.Repo.InvalidateUserTokens(userID, repository.TokenPasswordReset); err != nil {
+		return err
+	}
+
+	// A successful reset proves ownership, so lift any lockout of the account
+	if err := s.Repo.ClearLoginFailures(repository.ThrottleAccount, normalizeLogin(user.Username)); err != nil {
+		return err
+	}
+	if err := s.Repo.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	s.auditAccount(ctx, "user.password_reset", before, userID)
+	return nil
+}
+
+// VerifyEmail confirms a user's email address using a token from a verification email
+func (s *Service) VerifyEmail(token string) error {
+	userID, err := s.Repo.ConsumeUserToken(repository.TokenEmailVerification, hashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.MarkEmailVerified(userID); err != nil {
+		return err
+	}
+	return s.Repo.InvalidateUserTokens(userID, repository.TokenEmailVerification)
+}
+
+// ResendEmailVerification sends a new verification link to the user
+func (s *Service) ResendEmailVerification(userID int) error {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return apperr.Invalid("no email address on file")
+	}
+	if user.EmailVerified {
+		return apperr.Conflict("email already verified")
+	}
+	return s.sendEmailVerification(user)
+}
+
+func (s *Service) sendEmailVerification(user models.User) error {
+	token, err := s.issueUserToken(user.ID, repository.TokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	s.sendAsync(mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение почты — Free Toilet Map",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+			"Подтвердите адрес электронной почты, перейдя по ссылке (действует 48 часов):\n%s\n",
+			user.Username, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// issueUserToken creates a random single-use token and stores its hash
+func (s *Service) issueUserToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.Repo.CreateUserToken(userID, purpose, hashToken(token), time.Now().UTC().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendAsync sends an email in the background. Requests must not wait for
+// the mail server, and their timing must not reveal whether a mail was sent.
+func (s *Service) sendAsync(msg mail.Message) {
+	if s.Mailer == nil {
+		log.Printf("no mailer configured, dropping email to %s", msg.To)
+		return
+	}
+	go func() {
+		if err := s.Mailer.Send(msg); err != nil {
+			log.Printf("could not send email to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+func (s *Service) link(path, token string) string {
+	return strings.TrimRight(s.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// normalizeEmail validates an email address and strips any display name
+func normalizeEmail(email string) (string, error) {
+	addr, err := netmail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", apperr.Invalid("invalid email address")
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ExportUserData collects all data stored about a user
+func (s *Service) ExportUserData(userID int) (models.UserExport, error) {
+	export := models.UserExport{ExportedAt: time.Now().UTC()}
+
+	var err error
+	if export.Profile, err = s.Repo.GetUserByID(userID); err != nil {
+		return export, err
+	}
+	if export.Toilets, err = s.Repo.GetToiletsByFounder(userID); err != nil {
+		return export, err
+	}
+	if export.Reviews, err = s.Repo.GetReviewsByUser(userID); err != nil {
+		return export, err
+	}
+	if export.Comments, err = s.Repo.GetCommentsByUser(userID); err != nil {
+		return export, err
+	}
+	if export.Lists, err = s.Repo.GetListsByUser(userID); err != nil {
+		return export, err
+	}
+	if export.Identities, err = s.Repo.GetIdentitiesByUser(userID); err != nil {
+		return export, err
+	}
+	if export.APIKeys, err = s.Repo.GetAPIKeysByUser(userID); err != nil {
+		return export, err
+	}
+	if export.Ownerships, err = s.Repo.GetOwnershipsByUser(userID); err != nil {
+		return export, err
+	}
+	if export.Sessions, err = s.Repo.GetSessionsByUser(userID); err != nil {
+		return export, err
+	}
+	for i := range export.Sessions {
+		export.Sessions[i].Device = describeUserAgent(export.Sessions[i].UserAgent)
+	}
+	if export.TwoFactor, err = s.GetTOTPStatus(userID); err != nil {
+		return export, err
+	}
+	if export.Reports, err = s.Repo.GetReportsByReporter(userID); err != nil {
+		return export, err
+	}
+	if export.Warnings, err = s.Repo.GetUserWarnings(userID); err != nil {
+		return export, err
+	}
+	if export.Bans, err = s.Repo.GetUserBans(userID); err != nil {
+		return export, err
+	}
+	if export.Appeals, err = s.Repo.GetAppealsByUser(userID); err != nil {
+		return export, err
+	}
+	if export.AuditLog, err = s.Repo.QueryAuditLog(models.AuditFilter{ActorID: userID}); err != nil {
+		return export, err
+	}
+	for i := range export.AuditLog {
+		export.AuditLog[i].Before = nil
+		export.AuditLog[i].After = nil
+	}
+	return export, nil
+}
+
+// DeleteAccount deletes the user's account after confirming their password,
+// or a token from signing in again through a linked provider, see
+// StartReauthentication. Reviews are anonymized and founded toilets are
+// kept under a system user.
+func (s *Service) DeleteAccount(userID int, password, reauthToken string) error {
+	if reauthToken != "" {
+		tokenUserID, err := s.Repo.ConsumeUserToken(repository.TokenReauthentication, hashToken(reauthToken), time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if tokenUserID != userID {
+			return auth.ErrInvalidCredentials
+		}
+		return s.Repo.DeleteUser(userID)
+	}
+
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return auth.ErrInvalidCredentials
+	}
+
+	return s.Repo.DeleteUser(userID)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	models "free_toilet_map/toilet/model"
+	"log"
+	"time"
+)
+
+// Scores a spree consists of: the lowest and the highest
+const (
+	extremeLowScore  = 1
+	extremeHighScore = 5
+)
+
+// RatingAnomalyRules are the thresholds of the review bombing detection.
+// Every new review is checked against sliding windows over the reviews of
+// its toilet and of its author.
+type RatingAnomalyRules struct {
+	// A burst is BurstMinAccounts or more accounts younger than
+	// NewAccountAge giving a toilet a score of at most BurstMaxScore within
+	// BurstWindow. Toilets whose earlier reviews (at least
+	// BaselineMinReviews of them) average below BaselineMinScore are just
+	// bad, not bombed.
+	BurstWindow        time.Duration
+	BurstMinAccounts   int
+	BurstMaxScore      float32
+	NewAccountAge      time.Duration
+	BaselineMinReviews int
+	BaselineMinScore   float64
+
+	// A spree is one user giving SpreeMinReviews or more extreme scores
+	// within SpreeWindow
+	SpreeWindow     time.Duration
+	SpreeMinReviews int
+
+	// ExcludeFlagged leaves flagged reviews out of ratings until a
+	// moderator found them fine
+	ExcludeFlagged bool
+}
+
+// DefaultRatingAnomalyRules are used unless configured otherwise
+var DefaultRatingAnomalyRules = RatingAnomalyRules{
+	BurstWindow:        24 * time.Hour,
+	BurstMinAccounts:   3,
+	BurstMaxScore:      2,
+	NewAccountAge:      7 * 24 * time.Hour,
+	BaselineMinReviews: 3,
+	BaselineMinScore:   3,
+	SpreeWindow:        10 * time.Minute,
+	SpreeMinReviews:    5,
+	ExcludeFlagged:     true,
+}
+
+// detectRatingAnomalies checks whether a new review is part of a burst on
+// its toilet or a spree of its author and flags all reviews involved for
+// moderation. Errors are only logged since the review is already stored.
+func (s *Service) detectRatingAnomalies(review models.Review) {
+	now := time.Now().UTC()
+	if err := s.detectBurst(review.ToiletID, now); err != nil {
+		log.Printf("could not check toilet %d for a review burst: %v", review.ToiletID, err)
+	}
+	if err := s.detectSpree(review.UserID, now); err != nil {
+		log.Printf("could not check user %d for a review spree: %v", review.UserID, err)
+	}
+}
+
+func (s *Service) detectBurst(toiletID int, now time.Time) error {
+	rules := s.Anomalies
+	since := now.Add(-rules.BurstWindow)
+	ids, accounts, err := s.Repo.GetLowScoresFromNewAccounts(toiletID, since, rules.BurstMaxScore, now.Add(-rules.NewAccountAge))
+	if err != nil || accounts < rules.BurstMinAccounts {
+		return err
+	}
+
+	count, mean, err := s.Repo.GetRatingBaseline(toiletID, since)
+	if err != nil {
+		return err
+	}
+	if count >= rules.BaselineMinReviews && mean < rules.BaselineMinScore {
+		return nil
+	}
+
+	details := fmt.Sprintf("%d new accounts gave this toilet %g stars or less within %s", accounts, rules.BurstMaxScore, rules.BurstWindow)
+	if count > 0 {
+		details += fmt.Sprintf(", its %d earlier reviews average %.1f", count, mean)
+	}
+	return s.flagReviews(ids, models.AnomalyBurst, details)
+}
+
+func (s *Service) detectSpree(userID int, now time.Time) error {
+	rules := s.Anomalies
+	ids, err := s.Repo.GetExtremeScoresSince(userID, now.Add(-rules.SpreeWindow), extremeLowScore, extremeHighScore)
+	if err != nil || len(ids) < rules.SpreeMinReviews {
+		return err
+	}
+
+	details := fmt.Sprintf("the author gave %d extreme scores within %s", len(ids), rules.SpreeWindow)
+	return s.flagReviews(ids, models.AnomalySpree, details)
+}
+
+// flagReviews marks reviews as suspicious and puts those not flagged
+// before into the moderation queue
+func (s *Service) flagReviews(reviewIDs []int, anomaly, details string) error {
+	flagged, err := s.Repo.FlagReviewAnomalies(reviewIDs, anomaly)
+	if err != nil {
+		return err
+	}
+	for _, id := range flagged {
+		_, err := s.Repo.AddReport(models.Report{
+			TargetType: models.TargetReview,
+			TargetID:   id,
+			Reason:     models.ReasonAnomaly,
+			Details:    details,
+		}, 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyPrefix       = "ftm_"
+	defaultAPIKeyQuota = 1000 // requests per UTC day
+	maxActiveAPIKeys   = 10
+)
+
+// CreateAPIKey issues a new API key for a user. The returned key carries
+// the secret, which is not stored and cannot be shown again.
+func (s *Service) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	key.Name = strings.TrimSpace(key.Name)
+	key.Organization = strings.TrimSpace(key.Organization)
+	if key.Name == "" {
+		return models.APIKey{}, apperr.Invalid("api key name is required")
+	}
+	if len(key.Scopes) == 0 {
+		return models.APIKey{}, apperr.Invalid("at least one scope is required")
+	}
+	for _, scope := range key.Scopes {
+		if !auth.ValidScope(auth.Scope(scope)) {
+			return models.APIKey{}, apperr.Invalid("unknown scope: " + scope)
+		}
+	}
+
+	count, err := s.Repo.CountActiveAPIKeys(key.UserID)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if count >= maxActiveAPIKeys {
+		return models.APIKey{}, apperr.Conflict("too many active api keys")
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	plain := apiKeyPrefix + secret
+
+	key.Prefix = plain[:len(apiKeyPrefix)+6]
+	key.DailyQuota = defaultAPIKeyQuota
+
+	created, err := s.Repo.CreateAPIKey(key, hashToken(plain))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	created.Key = plain
+	return created, nil
+}
+
+// GetAPIKeys lists the API keys of a user
+func (s *Service) GetAPIKeys(userID int) ([]models.APIKey, error) {
+	return s.Repo.GetAPIKeysByUser(userID)
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+func (s *Service) RevokeAPIKey(keyID, userID int) error {
+	return s.Repo.RevokeAPIKey(keyID, userID)
+}
+
+// SetAPIKeyQuota changes the daily quota of a key (admins only)
+func (s *Service) SetAPIKeyQuota(keyID, dailyQuota int) error {
+	if dailyQuota < 0 {
+		return apperr.Invalid("quota must not be negative")
+	}
+	return s.Repo.SetAPIKeyQuota(keyID, dailyQuota)
+}
+
+// AuthenticateAPIKey checks an API key, its scope and its daily quota.
+// Every successful call counts as one request against the quota.
+func (s *Service) AuthenticateAPIKey(plain string, scope auth.Scope) (auth.Claims, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return auth.Claims{}, apperr.Unauthorized("invalid api key")
+	}
+
+	key, err := s.Repo.GetActiveAPIKeyByHash(hashToken(plain))
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	if !hasScope(key.Scopes, scope) {
+		return auth.Claims{}, auth.ErrInsufficientScope
+	}
+	if err := s.checkBanned(key.UserID); err != nil {
+		return auth.Claims{}, err
+	}
+
+	requests, err := s.Repo.RecordAPIKeyUsage(key.ID, time.Now().UTC())
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if requests > key.DailyQuota {
+		return auth.Claims{}, auth.ErrQuotaExceeded
+	}
+
+	// Keys act on behalf of their owner, but never with elevated privileges
+	return auth.Claims{UserID: key.UserID, Role: auth.RoleUser, APIKeyID: key.ID}, nil
+}
+
+func hasScope(scopes []string, scope auth.Scope) bool {
+	for _, s := range scopes {
+		if auth.Scope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/mail"
+	models "free_toilet_map/toilet/model"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultToiletConfirmations = 3
+
+// ToiletApproval configures how new toilets are approved. Toilets of
+// trusted users are approved right away; all others wait for a moderator
+// or for Confirmations other users.
+type ToiletApproval struct {
+	// Confirmations needed to approve a toilet, 0 leaves approval to
+	// moderators
+	Confirmations int
+	// ShowPending lists pending toilets on the map, marked as unverified.
+	// Otherwise only users who may confirm them see them on the map.
+	ShowPending bool
+}
+
+// NewToiletApprovalFromEnv reads TOILET_CONFIRMATIONS and
+// SHOW_PENDING_TOILETS
+func NewToiletApprovalFromEnv() ToiletApproval {
+	a := ToiletApproval{Confirmations: defaultToiletConfirmations}
+	if v := os.Getenv("TOILET_CONFIRMATIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("invalid TOILET_CONFIRMATIONS %q, using %d", v, a.Confirmations)
+		} else {
+			a.Confirmations = n
+		}
+	}
+	a.ShowPending = os.Getenv("SHOW_PENDING_TOILETS") == "true"
+	return a
+}
+
+// initialApproval is the approval state of a toilet added by a user with
+// the given reputation
+func initialApproval(rep models.Reputation) string {
+	if rep.Level == models.ReputationTrusted {
+		return models.ApprovalApproved
+	}
+	return models.ApprovalPending
+}
+
+// showsPending reports whether viewerID sees the toilets awaiting approval
+// on the map: everyone does if so configured, and otherwise the users who
+// may confirm them, who could not find them else
+func (s *Service) showsPending(viewerID int) (bool, error) {
+	if s.Approval.ShowPending {
+		return true, nil
+	}
+	if s.Approval.Confirmations == 0 || viewerID == 0 {
+		return false, nil
+	}
+	rep, err := s.GetReputation(viewerID)
+	if err != nil {
+		return false, err
+	}
+	return rep.Level != models.ReputationNew, nil
+}
+
+// ConfirmToilet records that a user has seen a pending toilet in place.
+// Once enough users confirmed it, the toilet is approved. Founders cannot
+// confirm their own toilets and new accounts cannot confirm at all, so
+// that a handful of fresh accounts cannot approve a fake toilet.
+// Confirmations of shadow-banned users are recorded but do not count.
+func (s *Service) ConfirmToilet(toiletID, userID int) (models.ToiletConfirmation, error) {
+	required := s.Approval.Confirmations
+	if required == 0 {
+		return models.ToiletConfirmation{}, apperr.Forbidden("toilets are approved by moderators only")
+	}
+
+	// Users who cannot confirm are turned away before the toilet is looked
+	// up, so they cannot find out which pending toilets exist
+	rep, err := s.GetReputation(userID)
+	if err != nil {
+		return models.ToiletConfirmation{}, err
+	}
+	if rep.Level == models.ReputationNew {
+		return models.ToiletConfirmation{}, apperr.Forbidden("your account is too new to confirm toilets")
+	}
+
+	toilet, err := s.Repo.GetToilet(toiletID)
+	if err != nil {
+		return models.ToiletConfirmation{}, err
+	}
+	if toilet.Status != models.StatusVisible {
+		return models.ToiletConfirmation{}, apperr.NotFound("toilet not found")
+	}
+	if toilet.Approval != models.ApprovalPending {
+		return models.ToiletConfirmation{}, apperr.Conflict("toilet is not awaiting approval")
+	}
+	if toilet.FounderID == userID {
+		return models.ToiletConfirmation{}, apperr.Forbidden("cannot confirm your own toilet")
+	}
+
+	count, err := s.Repo.AddToiletConfirmation(toiletID, userID)
+	if err != nil {
+		return models.ToiletConfirmation{}, err
+	}
+	result := models.ToiletConfirmation{
+		ToiletID:      toiletID,
+		Confirmations: count,
+		Required:      required,
+		Approval:      models.ApprovalPending,
+	}
+	if count < required {
+		return result, nil
+	}
+
+	err = s.Repo.SetToiletApproval(toiletID, models.ApprovalPending, models.ApprovalApproved, "", nil, time.Now().UTC())
+	if err != nil {
+		return models.ToiletConfirmation{}, err
+	}
+	result.Approval = models.ApprovalApproved
+	return result, nil
+}
+
+// PendingToilets lists toilets waiting for approval, oldest first
+func (s *Service) PendingToilets(limit, offset int) ([]models.PendingToilet, error) {
+	if limit <= 0 {
+		limit = defaultQueuePageSize
+	}
+	if limit > maxQueuePageSize {
+		limit = maxQueuePageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.Repo.GetPendingToilets(limit, offset)
+}
+
+// ApproveToilet publishes a pending toilet
+func (s *Service) ApproveToilet(toiletID, moderatorID int) error {
+	return s.Repo.SetToiletApproval(toiletID, models.ApprovalPending, models.ApprovalApproved, "", &moderatorID, time.Now().UTC())
+}
+
+// RejectToilet rejects a pending toilet. The founder sees the reason and
+// can fix the toilet, which submits it again.
+func (s *Service) RejectToilet(toiletID, moderatorID int, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return apperr.Invalid("a reason is required")
+	}
+	toilet, err := s.Repo.GetToilet(toiletID)
+	if err != nil {
+		return err
+	}
+	err = s.Repo.SetToiletApproval(toiletID, models.ApprovalPending, models.ApprovalRejected, reason, &moderatorID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	founder, err := s.Repo.GetUserByID(toilet.FounderID)
+	if err != nil {
+		log.Printf("could not tell the founder of toilet %d about its rejection: %v", toiletID, err)
+		return nil
+	}
+	if founder.Email != "" {
+		s.sendAsync(mail.Message{
+			To:      founder.Email,
+			Subject: "Туалет не прошёл проверку — Free Toilet Map",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+				"Модератор отклонил добавленный вами туалет «%s»:\n%s\n\n"+
+				"Вы можете исправить его в личном кабинете, после этого он снова будет отправлен на проверку.\n",
+				founder.Username, toilet.Name, reason),
+		})
+	}
+	return nil
+}
+
+// resubmitToilet puts a toilet back into approval after its founder
+// edited it: rejected toilets are submitted again, and approved ones that
+// were moved must be approved again unless the founder is trusted
+func (s *Service) resubmitToilet(before, after models.Toilet, rep models.Reputation) error {
+	switch {
+	case before.Approval == models.ApprovalRejected:
+	case before.Approval == models.ApprovalApproved && before.Point != after.Point && rep.Level != models.ReputationTrusted:
+	default:
+		return nil
+	}
+	return s.Repo.ResetToiletApproval(before.ID)
+}
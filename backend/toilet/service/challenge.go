@@ -0,0 +1,61 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/challenge"
+	"time"
+)
+
+// Challenges get harder when registrations or logins look scripted: one
+// bit more for every few failed logins of the client, and a few bits more
+// for everyone during a surge of failed logins or new accounts.
+const (
+	challengeFailuresPerBit = 5
+	challengeSurgeWindow    = time.Hour
+	challengeSurgeFailures  = 200
+	challengeSurgeSignups   = 50
+	challengeSurgeBits      = 2
+)
+
+// NewChallenge issues a proof-of-work challenge to a client before it
+// registers or signs in. With challenges turned off the challenge has a
+// difficulty of 0 and nothing needs to be solved.
+func (s *Service) NewChallenge(purpose, clientIP string) (challenge.Challenge, error) {
+	if purpose != challenge.PurposeLogin && purpose != challenge.PurposeRegister {
+		return challenge.Challenge{}, apperr.Invalid("invalid challenge purpose")
+	}
+	if s.Challenges == nil {
+		return challenge.Challenge{Algorithm: "sha256"}, nil
+	}
+
+	difficulty, err := s.challengeDifficulty(purpose, clientIP)
+	if err != nil {
+		return challenge.Challenge{}, err
+	}
+	return s.Challenges.Issue(purpose, clientIP, difficulty, time.Now().UTC())
+}
+
+// VerifyChallenge checks the solution of a challenge sent along with a
+// registration or login
+func (s *Service) VerifyChallenge(purpose, clientIP, token, nonce string) error {
+	if s.Challenges == nil {
+		return nil
+	}
+	return s.Challenges.Verify(token, nonce, purpose, clientIP, time.Now().UTC())
+}
+
+// challengeDifficulty raises the normal difficulty by the signs of abuse
+func (s *Service) challengeDifficulty(purpose, clientIP string) (int, error) {
+	now := time.Now().UTC()
+	signals, err := s.Repo.GetAbuseSignals(clientIP, now.Add(-loginFailureWindow), now.Add(-challengeSurgeWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	difficulty := s.Challenges.Difficulty + signals.IPFailures/challengeFailuresPerBit
+	if signals.AccountFailures >= challengeSurgeFailures ||
+		(purpose == challenge.PurposeRegister && signals.Signups >= challengeSurgeSignups) {
+		difficulty += challengeSurgeBits
+	}
+	return difficulty, nil
+}
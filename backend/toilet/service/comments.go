@@ -0,0 +1,101 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 2000
+
+// CommentHook lets moderation inspect a comment before it is stored.
+// Returning an error rejects the comment; a hook may also hold it back
+// from publication by setting its status to models.CommentHidden.
+type CommentHook func(comment *models.Comment) error
+
+// AddComment adds a reply under a review
+func (s *Service) AddComment(comment models.Comment) (models.Comment, error) {
+	if comment.ReviewID == 0 || comment.UserID == 0 {
+		return models.Comment{}, apperr.Invalid("missing required fields")
+	}
+	comment.Status = models.CommentVisible
+	if err := s.prepareComment(&comment); err != nil {
+		return models.Comment{}, err
+	}
+	saved, err := s.Repo.AddComment(comment)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if err := s.holdComment(saved.ID, comment.HoldReasons); err != nil {
+		return models.Comment{}, err
+	}
+	return saved, nil
+}
+
+// UpdateComment edits a comment. Only its author may do so. Editing never
+// publishes a hidden comment, but a hook may hide the edited one.
+func (s *Service) UpdateComment(comment models.Comment) (models.Comment, error) {
+	if comment.ID == 0 || comment.UserID == 0 {
+		return models.Comment{}, apperr.Invalid("missing required fields")
+	}
+	comment.Status = ""
+	if err := s.prepareComment(&comment); err != nil {
+		return models.Comment{}, err
+	}
+	if err := s.Repo.UpdateComment(comment); err != nil {
+		return models.Comment{}, err
+	}
+	if err := s.holdComment(comment.ID, comment.HoldReasons); err != nil {
+		return models.Comment{}, err
+	}
+	return s.Repo.GetComment(comment.ID)
+}
+
+// DeleteComment deletes a comment. Only its author may do so.
+func (s *Service) DeleteComment(commentID, userID int) error {
+	return s.Repo.DeleteComment(commentID, userID)
+}
+
+// GetCommentsByReview retrieves the visible comments of a review, as seen
+// by viewerID, see ListToilets
+func (s *Service) GetCommentsByReview(reviewID, viewerID int) ([]models.Comment, error) {
+	return s.Repo.GetCommentsByReview(reviewID, viewerID)
+}
+
+// HideComment removes a comment from public view without deleting it
+func (s *Service) HideComment(commentID int) error {
+	return s.Repo.SetCommentStatus(commentID, models.CommentHidden)
+}
+
+// RestoreComment makes a hidden comment visible again
+func (s *Service) RestoreComment(commentID int) error {
+	return s.Repo.SetCommentStatus(commentID, models.CommentVisible)
+}
+
+// holdComment puts a comment a hook held back into the moderation queue
+func (s *Service) holdComment(commentID int, reasons []string) error {
+	if len(reasons) == 0 {
+		return nil
+	}
+	return s.holdForModeration(models.TargetComment, commentID, reasons)
+}
+
+// prepareComment normalizes and validates the comment text and runs the
+// moderation hooks
+func (s *Service) prepareComment(comment *models.Comment) error {
+	comment.Text = strings.TrimSpace(comment.Text)
+	if comment.Text == "" {
+		return apperr.Invalid("comment text is required")
+	}
+	if utf8.RuneCountInString(comment.Text) > maxCommentLength {
+		return apperr.Invalid("comment is too long")
+	}
+
+	for _, hook := range s.CommentHooks {
+		if err := hook(comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"strings"
+)
+
+// CreateList creates a named list of saved toilets for a user
+func (s *Service) CreateList(list models.ToiletList) (models.ToiletList, error) {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" {
+		return models.ToiletList{}, apperr.Invalid("list name is required")
+	}
+	if list.Visibility == "" {
+		list.Visibility = models.VisibilityPrivate
+	}
+	if !models.ValidVisibility(list.Visibility) {
+		return models.ToiletList{}, apperr.Invalid("invalid visibility")
+	}
+
+	slug, err := newSlug()
+	if err != nil {
+		return models.ToiletList{}, err
+	}
+	list.Slug = slug
+
+	return s.Repo.CreateList(list)
+}
+
+// UpdateList renames a list or changes its visibility
+func (s *Service) UpdateList(list models.ToiletList) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" {
+		return apperr.Invalid("list name is required")
+	}
+	if !models.ValidVisibility(list.Visibility) {
+		return apperr.Invalid("invalid visibility")
+	}
+	return s.Repo.UpdateList(list)
+}
+
+// DeleteList deletes one of the user's lists
+func (s *Service) DeleteList(listID, userID int) error {
+	return s.Repo.DeleteList(listID, userID)
+}
+
+// GetUserLists retrieves all lists of a user, including private ones
+func (s *Service) GetUserLists(userID int) ([]models.ToiletList, error) {
+	return s.Repo.GetListsByUser(userID)
+}
+
+// GetSharedList retrieves a list by its slug, as seen by viewerID, see
+// ListToilets. Private lists are never returned here, even to their owner.
+func (s *Service) GetSharedList(slug string, viewerID int) (models.ToiletList, error) {
+	list, err := s.Repo.GetListBySlug(slug, viewerID)
+	if err != nil {
+		return models.ToiletList{}, err
+	}
+	if list.Visibility == models.VisibilityPrivate {
+		return models.ToiletList{}, apperr.NotFound("list not found")
+	}
+	return list, nil
+}
+
+// AddListItem saves a toilet into one of the user's lists
+func (s *Service) AddListItem(item models.ListItem, userID int) (models.ListItem, error) {
+	if item.ListID == 0 || item.ToiletID == 0 {
+		return models.ListItem{}, apperr.Invalid("missing required fields")
+	}
+	return s.Repo.AddListItem(item, userID)
+}
+
+// UpdateListItem changes the position or note of a saved toilet
+func (s *Service) UpdateListItem(item models.ListItem, userID int) error {
+	if item.ListID == 0 || item.ToiletID == 0 {
+		return apperr.Invalid("missing required fields")
+	}
+	return s.Repo.UpdateListItem(item, userID)
+}
+
+// RemoveListItem removes a toilet from one of the user's lists
+func (s *Service) RemoveListItem(listID, toiletID, userID int) error {
+	return s.Repo.RemoveListItem(listID, toiletID, userID)
+}
+
+// newSlug generates a random, URL-safe identifier for sharing a list
+func newSlug() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
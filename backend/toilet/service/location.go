@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"math"
+)
+
+const earthRadius = 6371000.0 // meters
+
+// LocationRules are the thresholds of the location plausibility check of
+// new toilets and reviews. Distances are in meters.
+type LocationRules struct {
+	// Submissions within NearDistance of the toilet are near, all others
+	// are marked remote
+	NearDistance float64
+	// Submissions of users who are not trusted from farther away than
+	// HoldDistance wait for a moderator. Trusted users may import toilets
+	// from their desk.
+	HoldDistance float64
+	// MaxAccuracy caps how much the inaccuracy of the device location is
+	// taken into account, so that a vague location cannot count as near
+	MaxAccuracy float64
+}
+
+// DefaultLocationRules are used unless configured otherwise
+var DefaultLocationRules = LocationRules{
+	NearDistance: 300,
+	HoldDistance: 20000,
+	MaxAccuracy:  1000,
+}
+
+// validatePoint rejects toilet points that are not "lat,lng" on the globe
+func validatePoint(point string) error {
+	lat, lng, ok := models.ParsePoint(point)
+	if !ok || !validCoordinates(lat, lng) {
+		return apperr.Invalid("point must be \"lat,lng\"")
+	}
+	return nil
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// checkLocation compares the device location of a submission with the
+// point of a toilet and returns whether the submission must be held back.
+// Leaving the location out must not get a submission past the check, so
+// without one, or if the point of the toilet cannot be read, the result
+// is unknown and submissions of users who are not trusted are held back.
+func (s *Service) checkLocation(loc *models.DeviceLocation, point string, rep models.Reputation) (*models.LocationCheck, bool, error) {
+	unknown := &models.LocationCheck{Result: models.LocationUnknown}
+	if loc == nil {
+		return unknown, rep.Level != models.ReputationTrusted, nil
+	}
+	if !validCoordinates(loc.Lat, loc.Lng) || loc.Accuracy < 0 {
+		return nil, false, apperr.Invalid("invalid location")
+	}
+	lat, lng, ok := models.ParsePoint(point)
+	if !ok {
+		return unknown, rep.Level != models.ReputationTrusted, nil
+	}
+
+	check := &models.LocationCheck{
+		Result:   models.LocationNear,
+		Distance: math.Round(distance(loc.Lat, loc.Lng, lat, lng)),
+		Accuracy: math.Round(loc.Accuracy),
+	}
+	away := max(0, check.Distance-min(check.Accuracy, s.Location.MaxAccuracy))
+	if away <= s.Location.NearDistance {
+		return check, false, nil
+	}
+	check.Result = models.LocationRemote
+	hold := away > s.Location.HoldDistance && rep.Level != models.ReputationTrusted
+	return check, hold, nil
+}
+
+// holdForLocation puts a submission made far away from its toilet, or
+// without a location, into the moderation queue
+func (s *Service) holdForLocation(targetType string, targetID int, check *models.LocationCheck) error {
+	details := "submitted without a location that could be checked"
+	if check.Result != models.LocationUnknown {
+		details = fmt.Sprintf("submitted %.1f km away from the toilet (location accurate to %.0f m)", check.Distance/1000, check.Accuracy)
+	}
+	_, err := s.Repo.AddReport(models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     models.ReasonLocation,
+		Details:    details,
+	}, 1)
+	return err
+}
+
+// distance returns the great-circle distance between two points in meters
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(lat2 - lat1)
+	dLng := rad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(1, a)))
+}
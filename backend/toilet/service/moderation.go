@@ -0,0 +1,161 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/screening"
+	"strings"
+)
+
+// UpdateToilet updates a toilet. Only its founder may do so. Edits go
+// through the content filter like new toilets, and moving an approved
+// toilet or fixing a rejected one submits it for approval again.
+func (s *Service) UpdateToilet(toilet models.Toilet, userID int) error {
+	if err := validateToilet(toilet); err != nil {
+		return err
+	}
+	rep, err := s.GetReputation(userID)
+	if err != nil {
+		return err
+	}
+	status, reasons, err := s.screenContent(screening.Content{
+		Kind:   screening.KindToilet,
+		ID:     toilet.ID,
+		UserID: userID,
+		Fields: []string{toilet.Name, toilet.Address},
+	}, rep)
+	if err != nil {
+		return err
+	}
+	before, err := s.Repo.GetToilet(toilet.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.UpdateToilet(toilet, userID); err != nil {
+		return err
+	}
+	if err := s.resubmitToilet(before, toilet, rep); err != nil {
+		return err
+	}
+	if status != models.StatusPending {
+		return nil
+	}
+	if err := s.Repo.SetToiletStatus(toilet.ID, status); err != nil {
+		return err
+	}
+	return s.holdForModeration(models.TargetToilet, toilet.ID, reasons)
+}
+
+// UpdateToiletAsModerator updates any toilet
+func (s *Service) UpdateToiletAsModerator(toilet models.Toilet) error {
+	if err := validateToilet(toilet); err != nil {
+		return err
+	}
+	return s.Repo.UpdateToiletAsModerator(toilet)
+}
+
+// DeleteToiletAsModerator deletes any toilet
+func (s *Service) DeleteToiletAsModerator(toiletID int) error {
+	return s.Repo.DeleteToiletAsModerator(toiletID)
+}
+
+// UpdateReview updates a review. Only its author may do so. Edits go
+// through the content filter like new reviews.
+func (s *Service) UpdateReview(review models.Review, userID int) error {
+	if err := validateReview(review); err != nil {
+		return err
+	}
+	rep, err := s.GetReputation(userID)
+	if err != nil {
+		return err
+	}
+	status, reasons, err := s.screenContent(screening.Content{
+		Kind:   screening.KindReview,
+		ID:     review.ID,
+		UserID: userID,
+		Fields: []string{review.Title, review.ReviewText},
+	}, rep)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.UpdateReview(review, userID); err != nil {
+		return err
+	}
+	if status != models.StatusPending {
+		return nil
+	}
+	if err := s.Repo.SetReviewStatus(review.ID, status); err != nil {
+		return err
+	}
+	return s.holdForModeration(models.TargetReview, review.ID, reasons)
+}
+
+// UpdateReviewAsModerator updates any review
+func (s *Service) UpdateReviewAsModerator(review models.Review) error {
+	if err := validateReview(review); err != nil {
+		return err
+	}
+	return s.Repo.UpdateReviewAsModerator(review)
+}
+
+// DeleteReview deletes a review. Only its author may do so.
+func (s *Service) DeleteReview(reviewID, userID int) error {
+	return s.Repo.DeleteReview(reviewID, userID)
+}
+
+// DeleteReviewAsModerator deletes any review
+func (s *Service) DeleteReviewAsModerator(reviewID int) error {
+	return s.Repo.DeleteReviewAsModerator(reviewID)
+}
+
+// DeleteCommentAsModerator deletes any comment
+func (s *Service) DeleteCommentAsModerator(commentID int) error {
+	return s.Repo.DeleteCommentAsModerator(commentID)
+}
+
+// SetUserRole changes the role of another user. A user who loses
+// privileges is signed out everywhere so the old role stops working
+// immediately instead of when the access token expires.
+func (s *Service) SetUserRole(actorID, userID int, role auth.Role) error {
+	if !auth.ValidRole(role) {
+		return apperr.Invalid("invalid role")
+	}
+	if actorID == userID {
+		return apperr.Forbidden("cannot change your own role")
+	}
+
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.SetUserRole(userID, string(role)); err != nil {
+		return err
+	}
+
+	if !role.AtLeast(auth.Role(user.Role)) {
+		return s.Repo.RevokeUserSessions(userID)
+	}
+	return nil
+}
+
+func validateToilet(toilet models.Toilet) error {
+	if toilet.ID == 0 {
+		return apperr.Invalid("missing required fields")
+	}
+	if strings.TrimSpace(toilet.Name) == "" || strings.TrimSpace(toilet.Point) == "" {
+		return apperr.Invalid("name and point are required")
+	}
+	return validatePoint(toilet.Point)
+}
+
+func validateReview(review models.Review) error {
+	if review.ID == 0 {
+		return apperr.Invalid("missing required fields")
+	}
+	if review.Score < 0 || review.Score > 5 {
+		return apperr.Invalid("score must be between 0 and 5")
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/mail"
+	models "free_toilet_map/toilet/model"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxReportDetailsLength  = 1000
+	maxModerationNoteLength = 2000
+	defaultQueuePageSize    = 50
+	maxQueuePageSize        = 200
+)
+
+// Reasons users can give when reporting content
+var reportReasons = map[string]bool{
+	"spam":          true,
+	"fake":          true, // the toilet does not exist or the review is made up
+	"offensive":     true,
+	"inappropriate": true,
+	"wrong_info":    true,
+	"other":         true,
+}
+
+// Reasons of reports the service files itself
+var systemReasons = map[string]bool{
+	models.ReasonScreening: true,
+	models.ReasonAnomaly:   true,
+	models.ReasonLocation:  true,
+}
+
+// Actions a moderator can take to resolve a queue item
+const (
+	ActionDismiss = "dismiss"
+	ActionApprove = "approve" // publishes content held back or hidden
+	ActionHide    = "hide"
+	ActionDelete  = "delete"
+	ActionWarn    = "warn"
+	ActionBan     = "ban"        // suspends the author for BanDays days, or bans them for good
+	ActionShadow  = "shadow_ban" // hides the author's content from everyone else
+)
+
+// ReportContent files a report about a toilet, review, comment or user.
+// Reports about the same target are collected in one queue item, which is
+// prioritised by the reputation of the reporters. Reports of shadow-banned
+// users are accepted but dropped.
+func (s *Service) ReportContent(report models.Report) (models.Report, error) {
+	if report.ReporterID == nil || report.TargetID == 0 {
+		return models.Report{}, apperr.Invalid("missing required fields")
+	}
+	if !reportReasons[report.Reason] {
+		return models.Report{}, apperr.Invalid("invalid report reason")
+	}
+
+	report.Details = strings.TrimSpace(report.Details)
+	if report.Reason == "other" && report.Details == "" {
+		return models.Report{}, apperr.Invalid("please describe the problem")
+	}
+	if utf8.RuneCountInString(report.Details) > maxReportDetailsLength {
+		return models.Report{}, apperr.Invalid("report details are too long")
+	}
+
+	authorID, err := s.Repo.GetTargetAuthor(report.TargetType, report.TargetID)
+	if err != nil {
+		return models.Report{}, err
+	}
+	if report.TargetType == models.TargetUser && authorID == *report.ReporterID {
+		return models.Report{}, apperr.Forbidden("cannot report yourself")
+	}
+
+	rep, err := s.checkContributionLimit(*report.ReporterID, contributionReport)
+	if err != nil {
+		return models.Report{}, err
+	}
+	banned, err := s.Repo.IsShadowBanned(*report.ReporterID)
+	if err != nil {
+		return models.Report{}, err
+	}
+	if banned {
+		report.CreatedAt = time.Now().UTC()
+		return report, nil
+	}
+	return s.Repo.AddReport(report, contributionWeight(rep))
+}
+
+// ModerationQueue lists queue items. Without a state only open items are
+// listed.
+func (s *Service) ModerationQueue(filter models.ModerationFilter) ([]models.ModerationItem, error) {
+	switch filter.State {
+	case "":
+		filter.State = models.ItemOpen
+	case "all":
+		filter.State = ""
+	case models.ItemOpen, models.ItemActioned, models.ItemDismissed:
+	default:
+		return nil, apperr.Invalid("invalid state")
+	}
+	if filter.TargetType != "" {
+		if _, ok := targetNames[filter.TargetType]; !ok {
+			return nil, apperr.Invalid("invalid target type")
+		}
+	}
+	if filter.Reason != "" && !reportReasons[filter.Reason] && !systemReasons[filter.Reason] {
+		return nil, apperr.Invalid("invalid report reason")
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultQueuePageSize
+	}
+	if filter.Limit > maxQueuePageSize {
+		filter.Limit = maxQueuePageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.Repo.ListModerationItems(filter)
+}
+
+// GetModerationItem retrieves a queue item with all its reports and notes
+func (s *Service) GetModerationItem(itemID int) (models.ModerationItem, error) {
+	return s.Repo.GetModerationItem(itemID)
+}
+
+// AssignModerationItem hands an open item to a moderator. An assigneeID of
+// 0 releases the item.
+func (s *Service) AssignModerationItem(itemID, assigneeID int) error {
+	if assigneeID == 0 {
+		return s.Repo.AssignModerationItem(itemID, nil)
+	}
+
+	assignee, err := s.Repo.GetUserByID(assigneeID)
+	if err != nil {
+		return err
+	}
+	if !auth.Role(assignee.Role).Can(auth.PermModerateContent) {
+		return apperr.Invalid("assignee is not a moderator")
+	}
+	return s.Repo.AssignModerationItem(itemID, &assigneeID)
+}
+
+// AddModerationNote attaches a note of a moderator to a queue item
+func (s *Service) AddModerationNote(itemID, authorID int, text string) (models.ModerationNote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.ModerationNote{}, apperr.Invalid("note text is required")
+	}
+	if utf8.RuneCountInString(text) > maxModerationNoteLength {
+		return models.ModerationNote{}, apperr.Invalid("note is too long")
+	}
+	return s.Repo.AddModerationNote(models.ModerationNote{ItemID: itemID, AuthorID: &authorID, Text: text})
+}
+
+// ResolveModerationItem closes an open queue item. Dismissing leaves the
+// target alone; every other action is applied to the target (approve,
+// hide, delete) or to its author (warn, ban, shadow_ban). The item is
+// closed first, so that of two moderators resolving it at the same time
+// only one gets to act; it is opened again if the action fails.
+func (s *Service) ResolveModerationItem(moderatorID int, res models.Resolution) error {
+	state := models.ItemActioned
+	switch res.Action {
+	case ActionDismiss:
+		state = models.ItemDismissed
+	case ActionApprove, ActionHide, ActionDelete, ActionWarn, ActionBan, ActionShadow:
+	default:
+		return apperr.Invalid("invalid action")
+	}
+
+	item, err := s.Repo.GetModerationItem(res.ItemID)
+	if err != nil {
+		return err
+	}
+	if item.State != models.ItemOpen {
+		return apperr.Conflict("moderation item is already resolved")
+	}
+
+	// Remember whose content was acted against; it counts against their
+	// reputation even after the content is deleted
+	var targetUserID int
+	switch res.Action {
+	case ActionHide, ActionDelete, ActionWarn, ActionBan, ActionShadow:
+		if targetUserID, err = s.Repo.GetTargetAuthor(item.TargetType, item.TargetID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	if err := s.Repo.ResolveModerationItem(item.ID, state, res.Action, moderatorID, targetUserID, now); err != nil {
+		return err
+	}
+
+	res.Reason = strings.TrimSpace(res.Reason)
+	switch res.Action {
+	case ActionApprove:
+		err = s.approveTarget(item, moderatorID)
+	case ActionHide:
+		err = s.hideTarget(item)
+	case ActionDelete:
+		err = s.deleteTarget(item)
+	case ActionWarn:
+		err = s.warnAuthor(item, moderatorID, res.Reason)
+	case ActionBan:
+		kind := models.BanPermanent
+		if res.BanDays > 0 {
+			kind = models.BanSuspension
+		}
+		err = s.banAuthor(item, moderatorID, kind, res.Reason, res.BanDays)
+	case ActionShadow:
+		err = s.banAuthor(item, moderatorID, models.BanShadow, res.Reason, res.BanDays)
+	}
+	if err != nil {
+		if err := s.Repo.ReopenModerationItem(item.ID, now); err != nil {
+			log.Printf("could not reopen moderation item %d after its action failed: %v", item.ID, err)
+		}
+		return err
+	}
+
+	// A review found fine counts towards the rating again
+	if item.TargetType == models.TargetReview && (res.Action == ActionDismiss || res.Action == ActionApprove) {
+		if err := s.Repo.ClearReviewAnomaly(item.TargetID, now); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(res.Note) != "" {
+		if _, err := s.AddModerationNote(item.ID, moderatorID, res.Note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HideToilet removes a toilet from the map without deleting it
+func (s *Service) HideToilet(toiletID int) error {
+	return s.Repo.SetToiletStatus(toiletID, models.StatusHidden)
+}
+
+// RestoreToilet puts a hidden toilet back on the map
+func (s *Service) RestoreToilet(toiletID int) error {
+	return s.Repo.SetToiletStatus(toiletID, models.StatusVisible)
+}
+
+// HideReview removes a review from public view without deleting it
+func (s *Service) HideReview(reviewID int) error {
+	return s.Repo.SetReviewStatus(reviewID, models.StatusHidden)
+}
+
+// RestoreReview makes a hidden review visible again
+func (s *Service) RestoreReview(reviewID int) error {
+	return s.Repo.SetReviewStatus(reviewID, models.StatusVisible)
+}
+
+// targetNames are used in messages to users about their content
+var targetNames = map[string]string{
+	models.TargetToilet:  "туалет",
+	models.TargetReview:  "отзыв",
+	models.TargetComment: "комментарий",
+	models.TargetUser:    "профиль",
+}
+
+// approveTarget publishes the target. A toilet approved from the queue has
+// been checked by a moderator, so it is approved if it was pending, too.
+func (s *Service) approveTarget(item models.ModerationItem, moderatorID int) error {
+	switch item.TargetType {
+	case models.TargetToilet:
+		if err := s.RestoreToilet(item.TargetID); err != nil {
+			return err
+		}
+		toilet, err := s.Repo.GetToilet(item.TargetID)
+		if err != nil || toilet.Approval != models.ApprovalPending {
+			return err
+		}
+		return s.ApproveToilet(item.TargetID, moderatorID)
+	case models.TargetReview:
+		return s.RestoreReview(item.TargetID)
+	case models.TargetComment:
+		return s.RestoreComment(item.TargetID)
+	}
+	return apperr.Invalid("users cannot be approved, dismiss the item instead")
+}
+
+func (s *Service) hideTarget(item models.ModerationItem) error {
+	switch item.TargetType {
+	case models.TargetToilet:
+		return s.HideToilet(item.TargetID)
+	case models.TargetReview:
+		return s.HideReview(item.TargetID)
+	case models.TargetComment:
+		return s.HideComment(item.TargetID)
+	}
+	return apperr.Invalid("users cannot be hidden, warn or ban them instead")
+}
+
+func (s *Service) deleteTarget(item models.ModerationItem) error {
+	switch item.TargetType {
+	case models.TargetToilet:
+		return s.DeleteToiletAsModerator(item.TargetID)
+	case models.TargetReview:
+		return s.DeleteReviewAsModerator(item.TargetID)
+	case models.TargetComment:
+		return s.DeleteCommentAsModerator(item.TargetID)
+	}
+	return apperr.Invalid("users cannot be deleted, ban them instead")
+}
+
+// warnAuthor records a warning for the author of the reported content and
+// tells them by email if they have an address
+func (s *Service) warnAuthor(item models.ModerationItem, moderatorID int, reason string) error {
+	if reason == "" {
+		return apperr.Invalid("a reason is required")
+	}
+	author, err := s.targetAuthor(item, moderatorID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.AddUserWarning(author.ID, item.ID, moderatorID, reason); err != nil {
+		return err
+	}
+
+	if author.Email != "" {
+		s.sendAsync(mail.Message{
+			To:      author.Email,
+			Subject: "Предупреждение модератора — Free Toilet Map",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+				"Модератор проверил жалобу на ваш %s и вынес предупреждение:\n%s\n\n"+
+				"При повторных нарушениях аккаунт может быть заблокирован.\n",
+				author.Username, targetNames[item.TargetType], reason),
+		})
+	}
+	return nil
+}
+
+// banAuthor bans the author of the reported content, see BanUser
+func (s *Service) banAuthor(item models.ModerationItem, moderatorID int, kind, reason string, banDays int) error {
+	authorID, err := s.Repo.GetTargetAuthor(item.TargetType, item.TargetID)
+	if err != nil {
+		return err
+	}
+	itemID := item.ID
+	_, err = s.BanUser(moderatorID, models.Ban{UserID: authorID, Kind: kind, Reason: reason, ItemID: &itemID}, banDays)
+	return err
+}
+
+// targetAuthor looks up the user responsible for the target of an item.
+// Moderators cannot act against themselves.
+func (s *Service) targetAuthor(item models.ModerationItem, moderatorID int) (models.User, error) {
+	authorID, err := s.Repo.GetTargetAuthor(item.TargetType, item.TargetID)
+	if err != nil {
+		return models.User{}, err
+	}
+	return s.sanctionTarget(authorID, moderatorID)
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/oidc"
+	"free_toilet_map/toilet/repository"
+	"free_toilet_map/toilet/validation"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// oidcStateTTL is how long a user has to complete a "Sign in with..." flow
+	oidcStateTTL = 10 * time.Minute
+	// reauthTokenTTL is how long a user has to use the token that confirms
+	// who they are, see StartReauthentication
+	reauthTokenTTL = 5 * time.Minute
+)
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// ExternalProviders lists the names of the configured identity providers
+func (s *Service) ExternalProviders() []string {
+	names := []string{}
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartExternalLogin begins the authorization code flow with a provider and
+// returns the URL to send the user to and the state of the flow. The state
+// has to be kept in the browser that started the flow, see
+// CompleteExternalLogin. A non-zero linkUserID links the external account
+// to that user instead of signing in.
+func (s *Service) StartExternalLogin(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	return s.startExternalFlow(ctx, models.OIDCState{Provider: providerName, LinkUserID: linkUserID})
+}
+
+// StartReauthentication begins a flow in which a signed-in user confirms
+// who they are through a provider linked to their account, instead of
+// with their password, which accounts created through a provider do not
+// know. The flow ends with a single-use token, see DeleteAccount.
+func (s *Service) StartReauthentication(ctx context.Context, providerName string, userID int) (string, string, error) {
+	return s.startExternalFlow(ctx, models.OIDCState{Provider: providerName, ReauthUserID: userID})
+}
+
+// startExternalFlow stores the state of a flow with the provider of st and
+// returns the URL to send the user to and the state, see StartExternalLogin
+func (s *Service) startExternalFlow(ctx context.Context, st models.OIDCState) (string, string, error) {
+	provider, ok := s.Providers[st.Provider]
+	if !ok {
+		return "", "", apperr.NotFound("unknown identity provider")
+	}
+
+	now := time.Now().UTC()
+	st.ExpiresAt = now.Add(oidcStateTTL)
+	var err error
+	if st.State, err = randomToken(); err != nil {
+		return "", "", err
+	}
+	if st.Nonce, err = randomToken(); err != nil {
+		return "", "", err
+	}
+	if st.CodeVerifier, err = randomToken(); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, st.State, st.Nonce, st.CodeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.Repo.CreateOIDCState(st, now); err != nil {
+		return "", "", err
+	}
+	return authURL, st.State, nil
+}
+
+// CompleteExternalLogin finishes the flow started by StartExternalLogin:
+// it verifies the provider's ID token, finds or creates the linked user
+// and starts a normal session for them, or issues a 2FA challenge.
+// browserState is the state kept by the browser the callback arrived in.
+// It has to match, or else someone could have the browser of a victim
+// complete a flow they started, and sign the victim into their account or
+// link their identity to it.
+func (s *Service) CompleteExternalLogin(ctx context.Context, providerName, code, state, browserState string, client ClientInfo) (models.LoginResult, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return models.LoginResult{}, apperr.Invalid("invalid or expired login request")
+	}
+	st, err := s.Repo.ConsumeOIDCState(state, time.Now().UTC())
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	if st.Provider != providerName {
+		return models.LoginResult{}, apperr.Invalid("invalid or expired login request")
+	}
+
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return models.LoginResult{}, apperr.NotFound("unknown identity provider")
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, st.CodeVerifier)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	identity, err := provider.VerifyIDToken(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+
+	if st.ReauthUserID != 0 {
+		return s.reauthenticate(providerName, identity, st.ReauthUserID)
+	}
+	user, err := s.resolveExternalUser(ctx, providerName, identity, st.LinkUserID)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	return s.beginSession(user, client)
+}
+
+// reauthenticate finishes a flow started by StartReauthentication. The
+// identity has to be linked to the user already; nothing is linked or
+// created on the way.
+func (s *Service) reauthenticate(provider string, id oidc.Identity, userID int) (models.LoginResult, error) {
+	user, err := s.Repo.GetUserByIdentity(provider, id.Subject)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return models.LoginResult{}, err
+	}
+	if err != nil || user.ID != userID {
+		return models.LoginResult{}, apperr.Forbidden("this account is not linked to you")
+	}
+
+	token, err := s.issueUserToken(userID, repository.TokenReauthentication, reauthTokenTTL)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	return models.LoginResult{ReauthToken: token}, nil
+}
+
+// GetLinkedIdentities lists the external accounts linked to a user
+func (s *Service) GetLinkedIdentities(userID int) ([]models.LinkedIdentity, error) {
+	return s.Repo.GetIdentitiesByUser(userID)
+}
+
+// UnlinkIdentity removes an external account from a user
+func (s *Service) UnlinkIdentity(userID int, provider string) error {
+	return s.Repo.UnlinkIdentity(userID, provider)
+}
+
+// resolveExternalUser maps an external identity to a local user. Known
+// identities sign in directly. New identities are linked to the user who
+// started a link request, to an existing user whose verified email matches
+// the provider's verified email, or to a freshly created user. New users
+// and links are recorded in the audit log.
+func (s *Service) resolveExternalUser(ctx context.Context, provider string, id oidc.Identity, linkUserID int) (models.User, error) {
+	user, err := s.Repo.GetUserByIdentity(provider, id.Subject)
+	if err == nil {
+		if linkUserID != 0 && user.ID != linkUserID {
+			return models.User{}, repository.ErrIdentityLinked
+		}
+		return user, nil
+	}
+
+	switch {
+	case linkUserID != 0:
+		user, err = s.Repo.GetUserByID(linkUserID)
+
+	case id.Email != "" && id.EmailVerified:
+		user, err = s.Repo.GetUserByEmail(id.Email)
+		if err != nil || !user.EmailVerified {
+			user, err = s.createExternalUser(ctx, id)
+		}
+
+	default:
+		user, err = s.createExternalUser(ctx, id)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	before, err := s.AuditSnapshot(models.TargetUser, strconv.Itoa(user.ID))
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := s.Repo.LinkIdentity(user.ID, provider, id.Subject, id.Email); err != nil {
+		return models.User{}, err
+	}
+	s.auditAccount(ctx, "user.identity_link", before, user.ID)
+	return user, nil
+}
+
+// createExternalUser registers a user for an external identity. The
+// password is random, so the account can only be used through the
+// provider until the user sets a password via the reset flow.
+func (s *Service) createExternalUser(ctx context.Context, id oidc.Identity) (models.User, error) {
+	password, err := randomToken()
+	if err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, errors.New("failed to hash password")
+	}
+
+	username, err := s.uniqueUsername(id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{Username: username, Password: string(hashedPassword)}
+	if id.Email != "" && id.EmailVerified {
+		// The address is only stored if no one else uses it already
+		if _, err := s.Repo.GetUserByEmail(id.Email); err != nil {
+			user.Email = id.Email
+		}
+	}
+
+	created, err := s.Repo.CreateUser(user, validation.UsernameKey(username))
+	if err != nil {
+		return models.User{}, err
+	}
+	if created.Email != "" {
+		if err := s.Repo.MarkEmailVerified(created.ID); err != nil {
+			return models.User{}, err
+		}
+	}
+	s.auditAccount(ctx, "user.create", nil, created.ID)
+	return created, nil
+}
+
+// uniqueUsername derives a free username from the identity's claims
+func (s *Service) uniqueUsername(id oidc.Identity) (string, error) {
+	base := id.PreferredUsername
+	if base == "" && id.Email != "" {
+		base = strings.SplitN(id.Email, "@", 2)[0]
+	}
+	base = usernameUnsafe.ReplaceAllString(base, "")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < validation.UsernameMinLength || validation.CheckUsername(base).Has("username") {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		exists, err := s.Repo.UsernameExists(candidate, validation.UsernameKey(candidate))
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%04d", base, n.Int64())
+	}
+	return "", errors.New("could not find a free username")
+}
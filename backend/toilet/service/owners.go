@@ -0,0 +1,30 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"time"
+)
+
+// VerifyToiletOwner confirms that a user runs the venue of a toilet, after
+// a moderator has checked it outside the app. The comments of owners are
+// marked as such.
+func (s *Service) VerifyToiletOwner(toiletID, userID int) (models.ToiletOwner, error) {
+	if toiletID == 0 || userID == 0 {
+		return models.ToiletOwner{}, apperr.Invalid("missing required fields")
+	}
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.ToiletOwner{}, err
+	}
+	if user.Username == repository.DeletedUsername {
+		return models.ToiletOwner{}, apperr.Conflict("the user has deleted their account")
+	}
+	return s.Repo.VerifyToiletOwner(toiletID, userID, time.Now().UTC())
+}
+
+// RevokeToiletOwner takes the owner status of a toilet away from a user
+func (s *Service) RevokeToiletOwner(toiletID, userID int) error {
+	return s.Repo.RevokeToiletOwner(toiletID, userID)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+	"time"
+)
+
+// Points for each reputation factor. Positive factors are capped so that
+// volume alone cannot make an account trusted; penalties are not.
+const (
+	pointsPerYearOfAge    = 20.0
+	maxAgePoints          = 20.0
+	pointsPerToilet       = 3.0
+	pointsPerReview       = 1.0
+	maxContributionPoints = 50.0
+	pointsPerConfirmed    = 2.0
+	pointsPerDismissed    = -0.5
+	maxReportPoints       = 20.0
+	pointsPerRemoval      = -10.0
+	pointsPerWarning      = -15.0
+	pointsPerBan          = -40.0
+	maxReputation         = 100
+)
+
+// Level thresholds. Accounts stay new for a few days whatever they do.
+const (
+	minRegularScore   = 10
+	minRegularAgeDays = 3
+	minTrustedScore   = 60
+)
+
+// contributionReport is the kind of contribution of reports, next to
+// models.TargetToilet, models.TargetReview and models.TargetComment
+const contributionReport = "report"
+
+// newAccountLimits are the contributions a new account may make per day
+var newAccountLimits = map[string]int{
+	models.TargetToilet:  3,
+	models.TargetReview:  10,
+	models.TargetComment: 20,
+	contributionReport:   10,
+}
+
+// contributionLimitError is returned when a new account exceeds its daily
+// limits. transport.encodeError answers it with 429.
+type contributionLimitError struct {
+	kind  string
+	limit int
+}
+
+func (e contributionLimitError) Error() string {
+	return fmt.Sprintf("new accounts can add at most %d %ss a day, try again later", e.limit, e.kind)
+}
+
+// StatusCode makes the transport answer with 429 Too Many Requests
+func (e contributionLimitError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// GetReputation computes the reputation of a user
+func (s *Service) GetReputation(userID int) (models.Reputation, error) {
+	f, err := s.Repo.GetReputationFactors(userID, time.Now().UTC())
+	if err != nil {
+		return models.Reputation{}, err
+	}
+
+	score := min(float64(f.AccountAgeDays)/365*pointsPerYearOfAge, maxAgePoints)
+	score += min(float64(f.PublishedToilets)*pointsPerToilet+float64(f.PublishedReviews)*pointsPerReview, maxContributionPoints)
+	score += min(float64(f.ConfirmedReports)*pointsPerConfirmed+float64(f.DismissedReports)*pointsPerDismissed, maxReportPoints)
+	score += float64(f.RemovedContent)*pointsPerRemoval + float64(f.Warnings)*pointsPerWarning + float64(f.Bans)*pointsPerBan
+
+	rep := models.Reputation{
+		UserID:  userID,
+		Score:   max(0, min(int(score), maxReputation)),
+		Factors: f,
+	}
+	switch {
+	case auth.Role(f.Role).AtLeast(auth.RoleTrusted) || rep.Score >= minTrustedScore:
+		rep.Level = models.ReputationTrusted
+	case rep.Score >= minRegularScore && f.AccountAgeDays >= minRegularAgeDays:
+		rep.Level = models.ReputationRegular
+	default:
+		rep.Level = models.ReputationNew
+	}
+	return rep, nil
+}
+
+// contributionWeight is how much a review or report of a user counts:
+// 0.5 for a brand-new account up to 1.5 for the best reputation
+func contributionWeight(rep models.Reputation) float64 {
+	return 0.5 + float64(rep.Score)/maxReputation
+}
+
+// checkContributionLimit returns the reputation of a user and refuses the
+// contribution if a new account already made too many of its kind today
+func (s *Service) checkContributionLimit(userID int, kind string) (models.Reputation, error) {
+	rep, err := s.GetReputation(userID)
+	if err != nil {
+		return models.Reputation{}, err
+	}
+	if rep.Level != models.ReputationNew {
+		return rep, nil
+	}
+
+	limit := newAccountLimits[kind]
+	count, err := s.Repo.CountContributionsSince(userID, kind, time.Now().UTC().Add(-24*time.Hour))
+	if err != nil {
+		return models.Reputation{}, err
+	}
+	if count >= limit {
+		return models.Reputation{}, contributionLimitError{kind: kind, limit: limit}
+	}
+	return rep, nil
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	"free_toilet_map/toilet/mail"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	maxAppealLength = 2000
+	appealTokenTTL  = time.Hour
+)
+
+// bannedError is returned for suspended and banned accounts, when they
+// sign in and on every authenticated request. It is encoded as 403 with
+// the reason, so the user knows why and whether they can appeal. When
+// they sign in, it carries a token to appeal with, see AppealBan.
+type bannedError struct {
+	ban         models.Ban
+	appealToken string
+}
+
+func (e bannedError) Error() string {
+	if e.ban.ExpiresAt != nil {
+		return "this account is suspended until " + e.ban.ExpiresAt.Format(time.RFC3339)
+	}
+	return auth.ErrAccountBanned.Error()
+}
+
+// Unwrap lets errors.Is match auth.ErrAccountBanned
+func (e bannedError) Unwrap() error {
+	return auth.ErrAccountBanned
+}
+
+// StatusCode makes the transport answer with 403 Forbidden
+func (e bannedError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// MarshalJSON encodes the ban without the moderator
+func (e bannedError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error       string     `json:"error"`
+		Kind        string     `json:"kind"`
+		Reason      string     `json:"reason"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+		AppealToken string     `json:"appeal_token,omitempty"`
+	}{e.Error(), e.ban.Kind, e.ban.Reason, e.ban.ExpiresAt, e.appealToken})
+}
+
+// checkBanned fails with a bannedError while the user is suspended or
+// banned. Shadow bans do not lock the account.
+func (s *Service) checkBanned(userID int) error {
+	ban, err := s.Repo.GetLockingBan(userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return bannedError{ban: *ban}
+	}
+	return nil
+}
+
+// withAppealToken hands a token to appeal with to a banned user who has
+// just proven who they are by signing in. Users who signed up through a
+// provider have no password to appeal with otherwise.
+func (s *Service) withAppealToken(userID int, err error) error {
+	var banned bannedError
+	if !errors.As(err, &banned) {
+		return err
+	}
+	token, tokenErr := s.issueUserToken(userID, repository.TokenBanAppeal, appealTokenTTL)
+	if tokenErr != nil {
+		log.Printf("could not create appeal token for user %d: %v", userID, tokenErr)
+		return err
+	}
+	banned.appealToken = token
+	return banned
+}
+
+// BanUser imposes a ban on a user. Suspensions last days days and
+// permanent bans take 0; shadow bans can be limited or not. Suspended and
+// banned users are signed out everywhere and told the reason by email,
+// shadow-banned users are not told anything.
+func (s *Service) BanUser(moderatorID int, ban models.Ban, days int) (models.Ban, error) {
+	ban.Reason = strings.TrimSpace(ban.Reason)
+	if ban.Reason == "" {
+		return models.Ban{}, apperr.Invalid("a reason is required")
+	}
+	switch {
+	case ban.Kind != models.BanSuspension && ban.Kind != models.BanPermanent && ban.Kind != models.BanShadow:
+		return models.Ban{}, apperr.Invalid("invalid ban kind")
+	case days < 0:
+		return models.Ban{}, apperr.Invalid("invalid ban duration")
+	case ban.Kind == models.BanSuspension && days == 0:
+		return models.Ban{}, apperr.Invalid("suspensions need a duration")
+	case ban.Kind == models.BanPermanent && days != 0:
+		return models.Ban{}, apperr.Invalid("permanent bans have no duration, suspend the user instead")
+	}
+
+	user, err := s.sanctionTarget(ban.UserID, moderatorID)
+	if err != nil {
+		return models.Ban{}, err
+	}
+	if auth.Role(user.Role).AtLeast(auth.RoleModerator) {
+		return models.Ban{}, apperr.Forbidden("moderators cannot be banned, change their role first")
+	}
+
+	ban.ModeratorID = &moderatorID
+	ban.ExpiresAt = nil
+	if days > 0 {
+		t := time.Now().UTC().AddDate(0, 0, days)
+		ban.ExpiresAt = &t
+	}
+	saved, err := s.Repo.AddUserBan(ban)
+	if err != nil {
+		return models.Ban{}, err
+	}
+	if ban.Kind == models.BanShadow {
+		return saved, nil
+	}
+
+	if err := s.Repo.RevokeUserSessions(user.ID); err != nil {
+		log.Printf("could not sign out banned user %d: %v", user.ID, err)
+	}
+	if user.Email != "" {
+		until := "навсегда"
+		if saved.ExpiresAt != nil {
+			until = "до " + saved.ExpiresAt.Format("02.01.2006 15:04") + " (UTC)"
+		}
+		s.sendAsync(mail.Message{
+			To:      user.Email,
+			Subject: "Аккаунт заблокирован — Free Toilet Map",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+				"Модератор заблокировал ваш аккаунт %s по причине:\n%s\n\n"+
+				"Если вы считаете блокировку ошибочной, вы можете один раз обжаловать её на странице входа.\n",
+				user.Username, until, saved.Reason),
+		})
+	}
+	return saved, nil
+}
+
+// LiftBan ends a ban before it expires
+func (s *Service) LiftBan(banID, moderatorID int) error {
+	return s.Repo.LiftUserBan(banID, moderatorID, time.Now().UTC())
+}
+
+// GetUserBans lists the bans of a user for moderators
+func (s *Service) GetUserBans(userID int) ([]models.Ban, error) {
+	return s.Repo.GetUserBans(userID)
+}
+
+// sanctionTarget looks up the user a moderator acts against. Moderators
+// cannot act against themselves.
+func (s *Service) sanctionTarget(userID, moderatorID int) (models.User, error) {
+	if userID == moderatorID {
+		return models.User{}, apperr.Forbidden("cannot act against yourself")
+	}
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if user.Username == repository.DeletedUsername {
+		return models.User{}, apperr.Conflict("the author has deleted their account")
+	}
+	return user, nil
+}
+
+// AppealBan files an appeal against the suspension or ban of an account.
+// Banned users cannot sign in, so they identify themselves with the appeal
+// token they got when they tried, or with their password; failures count
+// against the login throttle like failed logins. Each ban can be appealed
+// once.
+func (s *Service) AppealBan(username, password, appealToken, text string, client ClientInfo) (models.Appeal, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.Appeal{}, apperr.Invalid("please explain why the ban should be lifted")
+	}
+	if utf8.RuneCountInString(text) > maxAppealLength {
+		return models.Appeal{}, apperr.Invalid("appeal is too long")
+	}
+
+	user, err := s.appellant(username, password, appealToken, client)
+	if err != nil {
+		return models.Appeal{}, err
+	}
+
+	ban, err := s.Repo.GetLockingBan(user.ID, time.Now().UTC())
+	if err != nil {
+		return models.Appeal{}, err
+	}
+	if ban == nil {
+		return models.Appeal{}, apperr.Conflict("this account is not banned")
+	}
+	return s.Repo.AddAppeal(models.Appeal{BanID: ban.ID, UserID: user.ID, Text: text})
+}
+
+// appellant identifies the user appealing a ban, see AppealBan
+func (s *Service) appellant(username, password, appealToken string, client ClientInfo) (models.User, error) {
+	if appealToken != "" {
+		userID, err := s.Repo.ConsumeUserToken(repository.TokenBanAppeal, hashToken(appealToken), time.Now().UTC())
+		if err != nil {
+			return models.User{}, err
+		}
+		return s.Repo.GetUserByID(userID)
+	}
+
+	keys := loginThrottleKeys(username, client.IP)
+	if err := s.checkLoginLocks(keys); err != nil {
+		return models.User{}, err
+	}
+	user, err := s.Repo.GetUserByUsername(username)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return models.User{}, err
+	}
+	hash := dummyPasswordHash()
+	if err == nil {
+		hash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || err != nil {
+		if err := s.recordLoginFailure(keys); err != nil {
+			return models.User{}, err
+		}
+		return models.User{}, auth.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// OpenAppeals lists the appeals waiting for a moderator, oldest first
+func (s *Service) OpenAppeals(limit, offset int) ([]models.Appeal, error) {
+	if limit <= 0 {
+		limit = defaultQueuePageSize
+	}
+	if limit > maxQueuePageSize {
+		limit = maxQueuePageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.Repo.GetOpenAppeals(limit, offset)
+}
+
+// DecideAppeal accepts an appeal, which lifts the ban, or rejects it with
+// a response. The user is told the decision by email.
+func (s *Service) DecideAppeal(appealID, moderatorID int, accept bool, response string) error {
+	response = strings.TrimSpace(response)
+	state := models.AppealAccepted
+	if !accept {
+		state = models.AppealRejected
+		if response == "" {
+			return apperr.Invalid("a response is required")
+		}
+	}
+
+	appeal, err := s.Repo.GetAppeal(appealID)
+	if err != nil {
+		return err
+	}
+	if appeal.UserID == moderatorID {
+		return apperr.Forbidden("cannot act against yourself")
+	}
+	if err := s.Repo.DecideAppeal(appealID, state, response, moderatorID, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	user, err := s.Repo.GetUserByID(appeal.UserID)
+	if err != nil {
+		log.Printf("could not tell user %d about the decision on appeal %d: %v", appeal.UserID, appealID, err)
+		return nil
+	}
+	if user.Email != "" {
+		decision := "Блокировка снята, вы снова можете войти."
+		if !accept {
+			decision = "Блокировка остаётся в силе."
+		}
+		if response != "" {
+			decision += "\n\nОтвет модератора:\n" + response
+		}
+		s.sendAsync(mail.Message{
+			To:      user.Email,
+			Subject: "Решение по апелляции — Free Toilet Map",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+				"Модератор рассмотрел вашу апелляцию. %s\n",
+				user.Username, decision),
+		})
+	}
+	return nil
+}
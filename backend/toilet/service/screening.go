@@ -0,0 +1,83 @@
+package service
+
+import (
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/screening"
+	"strings"
+	"time"
+)
+
+const (
+	duplicateWindow  = 30 * 24 * time.Hour
+	duplicateHistory = 50
+)
+
+// RecentContent returns what the author of c posted recently. It is the
+// history used by screening.Duplicates.
+func (s *Service) RecentContent(c screening.Content) ([]string, error) {
+	if c.Kind != screening.KindReview {
+		return nil, nil
+	}
+	return s.Repo.GetRecentReviewTexts(c.UserID, c.ID, time.Now().UTC().Add(-duplicateWindow), duplicateHistory)
+}
+
+// screenContent runs the content filter and returns the status new content
+// is stored with. Rejected content is answered with screening.Rejected.
+// Trusted contributors are not held back for moderation.
+func (s *Service) screenContent(c screening.Content, rep models.Reputation) (string, []string, error) {
+	verdict, err := s.Screening.Screen(c)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := verdict.Err(); err != nil {
+		return "", nil, err
+	}
+	if verdict.Decision == screening.Hold && rep.Level != models.ReputationTrusted {
+		return models.StatusPending, verdict.Reasons, nil
+	}
+	return models.StatusVisible, nil, nil
+}
+
+// ScreenComment is a CommentHook that puts comments through the content
+// filter and the daily limits of new accounts, like toilets and reviews.
+// Comments the filter holds back stay hidden until a moderator approves
+// them.
+func (s *Service) ScreenComment(comment *models.Comment) error {
+	var rep models.Reputation
+	var err error
+	if comment.ID == 0 {
+		rep, err = s.checkContributionLimit(comment.UserID, models.TargetComment)
+	} else {
+		rep, err = s.GetReputation(comment.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, reasons, err := s.screenContent(screening.Content{
+		Kind:   screening.KindComment,
+		ID:     comment.ID,
+		UserID: comment.UserID,
+		Fields: []string{comment.Text},
+	}, rep)
+	if err != nil {
+		return err
+	}
+	if status == models.StatusPending {
+		comment.Status = models.CommentHidden
+		comment.HoldReasons = reasons
+	}
+	return nil
+}
+
+// holdForModeration puts content held back by the filter into the
+// moderation queue, see ActionApprove
+func (s *Service) holdForModeration(targetType string, targetID int, reasons []string) error {
+	_, err := s.Repo.AddReport(models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     models.ReasonScreening,
+		Details:    strings.Join(reasons, ", "),
+	}, 1)
+	return err
+}
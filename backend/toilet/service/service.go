@@ -1,24 +1,123 @@
 package service
 
 import (
-	"fmt"
+	"errors"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/challenge"
 	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/mail"
+	"free_toilet_map/toilet/oidc"
 	"free_toilet_map/toilet/repository"
+	"free_toilet_map/toilet/screening"
+	"free_toilet_map/toilet/token"
+	"free_toilet_map/toilet/validation"
 	"log"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-    Repo repository.PostgresRepository
+    Repo   repository.PostgresRepository
+    Tokens *token.Service
+    Mailer mail.Mailer
+
+    // Providers are the identity providers available for "Sign in with..."
+    Providers map[string]*oidc.Provider
+
+    // AppURL is the address of the frontend, used for links in emails
+    AppURL string
+
+    // CommentHooks are run on every new or edited comment, see CommentHook
+    CommentHooks []CommentHook
+
+    // Passwords checks new passwords, see validation.PasswordPolicy
+    Passwords *validation.PasswordPolicy
+
+    // Screening filters new toilets and reviews for spam and profanity
+    Screening screening.Pipeline
+
+    // Approval configures how new toilets are approved, see ToiletApproval
+    Approval ToiletApproval
+
+    // Anomalies are the thresholds of the review bombing detection
+    Anomalies RatingAnomalyRules
+
+    // Location are the thresholds of the location plausibility check
+    Location LocationRules
+
+    // Challenges issues the proof-of-work challenges for registration and
+    // login; nil turns them off
+    Challenges *challenge.Issuer
 }
 
-// NewService creates a new service instance with the provided repository
-func NewService(repo repository.PostgresRepository) *Service {
-    return &Service{Repo: repo}
+// NewService creates a new service instance with the provided repository, token service and mailer
+func NewService(repo repository.PostgresRepository, tokens *token.Service, mailer mail.Mailer) *Service {
+    return &Service{
+        Repo:      repo,
+        Tokens:    tokens,
+        Mailer:    mailer,
+        AppURL:    "http://localhost:3000",
+        Approval:  ToiletApproval{Confirmations: defaultToiletConfirmations},
+        Anomalies: DefaultRatingAnomalyRules,
+        Location:  DefaultLocationRules,
+    }
 }
 
-// CreateUser creates a new user by interacting with the repository
+// CreateUser registers a new user. user.Password is the plain password; it
+// is checked together with the username against the validation policies,
+// with problems reported per field, and hashed before it is stored.
+// If an email address was given, a verification link is sent to it.
 func (s *Service) CreateUser(user models.User) (models.User, error) {
-    return s.Repo.CreateUser(user)
+    user.Username = validation.NormalizeUsername(user.Username)
+    errs := validation.CheckUsername(user.Username)
+    errs = append(errs, s.Passwords.Check(user.Password, user.Username)...)
+
+    if user.Email != "" {
+        email, err := normalizeEmail(user.Email)
+        if err != nil {
+            errs.Add("email", "invalid", err.Error())
+        }
+        user.Email = email
+    }
+
+    usernameKey := validation.UsernameKey(user.Username)
+    if !errs.Has("username") {
+        exists, err := s.Repo.UsernameExists(user.Username, usernameKey)
+        if err != nil {
+            return models.User{}, err
+        }
+        if exists {
+            errs.Add("username", "taken", "username already exists")
+        }
+    }
+    if err := errs.Err(); err != nil {
+        return models.User{}, err
+    }
+
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+    if err != nil {
+        return models.User{}, errors.New("failed to hash password")
+    }
+    user.Password = string(hashedPassword)
+
+    created, err := s.Repo.CreateUser(user, usernameKey)
+    if err != nil {
+        // Taken by someone else since the check above
+        switch {
+        case errors.Is(err, repository.ErrUsernameTaken):
+            return models.User{}, validation.Errors{{Field: "username", Code: "taken", Message: err.Error()}}
+        case errors.Is(err, repository.ErrEmailTaken):
+            return models.User{}, validation.Errors{{Field: "email", Code: "taken", Message: err.Error()}}
+        }
+        return models.User{}, err
+    }
+
+    if created.Email != "" {
+        if err := s.sendEmailVerification(created); err != nil {
+            log.Printf("could not send verification email to user %d: %v", created.ID, err)
+        }
+    }
+    return created, nil
 }
 
 // GetUserByUsername retrieves a user by their username
@@ -26,14 +125,64 @@ func (s *Service) GetUserByUsername(username string) (models.User, error) {
     return s.Repo.GetUserByUsername(username)
 }
 
-// ListToilets retrieves all toilets from the repository
-func (s *Service) ListToilets() ([]models.Toilet, error) {
-    return s.Repo.GetAllToilets()
+// ListToilets retrieves all toilets from the repository. viewerID is the
+// signed-in user, or 0, who sees their own content while shadow-banned.
+// Users who may confirm toilets also see those awaiting approval.
+func (s *Service) ListToilets(viewerID int) ([]models.Toilet, error) {
+    withPending, err := s.showsPending(viewerID)
+    if err != nil {
+        return nil, err
+    }
+    return s.Repo.GetAllToilets(withPending, !s.Anomalies.ExcludeFlagged, viewerID)
 }
 
-// AddToilet adds a new toilet by interacting with the repository
+// AddToilet adds a new toilet. Toilets the content filter holds back,
+// and toilets added far away from where the device of the founder was
+// or without a location, are stored as pending and wait for a
+// moderator. Unless the founder is trusted, the toilet then waits for
+// approval, see ConfirmToilet.
 func (s *Service) AddToilet(toilet models.Toilet) (models.Toilet, error) {
-    return s.Repo.AddToilet(toilet)
+    if err := validatePoint(toilet.Point); err != nil {
+        return models.Toilet{}, err
+    }
+    rep, err := s.checkContributionLimit(toilet.FounderID, models.TargetToilet)
+    if err != nil {
+        return models.Toilet{}, err
+    }
+    status, reasons, err := s.screenContent(screening.Content{
+        Kind:   screening.KindToilet,
+        UserID: toilet.FounderID,
+        Fields: []string{toilet.Name, toilet.Address},
+    }, rep)
+    if err != nil {
+        return models.Toilet{}, err
+    }
+    check, farAway, err := s.checkLocation(toilet.Location, toilet.Point, rep)
+    if err != nil {
+        return models.Toilet{}, err
+    }
+    toilet.Status = status
+    if farAway {
+        toilet.Status = models.StatusPending
+    }
+    toilet.Approval = initialApproval(rep)
+    toilet.LocationCheck = check
+
+    saved, err := s.Repo.AddToilet(toilet)
+    if err != nil {
+        return models.Toilet{}, err
+    }
+    if status == models.StatusPending {
+        if err := s.holdForModeration(models.TargetToilet, saved.ID, reasons); err != nil {
+            return models.Toilet{}, err
+        }
+    }
+    if farAway {
+        if err := s.holdForLocation(models.TargetToilet, saved.ID, check); err != nil {
+            return models.Toilet{}, err
+        }
+    }
+    return saved, nil
 }
 
 // DeleteToilet deletes a toilet by interacting with the repository
@@ -42,20 +191,71 @@ func (s *Service) DeleteToilet(toiletID, userID int) error {
     return s.Repo.DeleteToilet(toiletID, userID)
 }
 
-// AddReview adds a review for a toilet
-func (s *Service) AddReview(review models.Review) error {
+// AddReview adds a review for a toilet. Reviews the content filter holds
+// back, and reviews written far away from the toilet or without a
+// location, are stored as pending and wait for a moderator. The review
+// counts towards the rating of the toilet according to the author's
+// reputation, unless it turns out to be part of review bombing.
+func (s *Service) AddReview(review models.Review) (models.Review, error) {
 
 	// Ensure all required fields are provided
 	if review.UserID == 0 || review.ToiletID == 0 {
-		return fmt.Errorf("missing required fields")
+		return models.Review{}, apperr.Invalid("missing required fields")
 	}
 
+	rep, err := s.checkContributionLimit(review.UserID, models.TargetReview)
+	if err != nil {
+		return models.Review{}, err
+	}
+	status, reasons, err := s.screenContent(screening.Content{
+		Kind:   screening.KindReview,
+		UserID: review.UserID,
+		Fields: []string{review.Title, review.ReviewText},
+	}, rep)
+	if err != nil {
+		return models.Review{}, err
+	}
+
+	var point string
+	if review.Location != nil {
+		toilet, err := s.Repo.GetToilet(review.ToiletID)
+		if err != nil {
+			return models.Review{}, err
+		}
+		point = toilet.Point
+	}
+	check, farAway, err := s.checkLocation(review.Location, point, rep)
+	if err != nil {
+		return models.Review{}, err
+	}
+	review.Status = status
+	if farAway {
+		review.Status = models.StatusPending
+	}
+	review.LocationCheck = check
+
 	// Add review to the database
-	return s.Repo.AddReview(review)
+	saved, err := s.Repo.AddReview(review, contributionWeight(rep))
+	if err != nil {
+		return models.Review{}, err
+	}
+	if status == models.StatusPending {
+		if err := s.holdForModeration(models.TargetReview, saved.ID, reasons); err != nil {
+			return models.Review{}, err
+		}
+	}
+	if farAway {
+		if err := s.holdForLocation(models.TargetReview, saved.ID, check); err != nil {
+			return models.Review{}, err
+		}
+	}
+	s.detectRatingAnomalies(saved)
+	return saved, nil
 }
 
 
-// GetReviewsByToilet retrieves all reviews for a specific toilet
-func (s *Service) GetReviewsByToilet(toiletID int) ([]models.Review, error) {
-    return s.Repo.GetReviewsByToilet(toiletID)
+// GetReviewsByToilet retrieves all reviews for a specific toilet, as seen
+// by viewerID, see ListToilets
+func (s *Service) GetReviewsByToilet(toiletID, viewerID int) ([]models.Review, error) {
+    return s.Repo.GetReviewsByToilet(toiletID, viewerID)
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	models "free_toilet_map/toilet/model"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionTouchInterval limits how often last_seen_at is written
+	sessionTouchInterval = 5 * time.Minute
+	maxUserAgentLength   = 512
+)
+
+// ClientInfo describes the device a request comes from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// GetSessions lists the user's active sessions. currentID marks the
+// session the request was made with.
+func (s *Service) GetSessions(userID int, currentID string) ([]models.Session, error) {
+	sessions, err := s.Repo.GetActiveSessions(userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Device = describeUserAgent(sessions[i].UserAgent)
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// touchSession records the use of a session, at most once per
+// sessionTouchInterval
+func (s *Service) touchSession(sessionID string) error {
+	now := time.Now().UTC()
+	return s.Repo.TouchSession(sessionID, now, now.Add(-sessionTouchInterval))
+}
+
+// browserNames and systemNames are checked in order; the first match wins, so more
+// specific names come before the ones they contain (Edge and Opera
+// identify as Chrome, Chrome as Safari)
+var (
+	browserNames = []struct{ token, name string }{
+		{"YaBrowser/", "Yandex Browser"},
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"okhttp/", "Android app"},
+		{"curl/", "curl"},
+	}
+	systemNames = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// describeUserAgent turns a user agent into something like "Chrome on
+// Android" for the session list
+func describeUserAgent(ua string) string {
+	var browser, system string
+	for _, b := range browserNames {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range systemNames {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
+
+// truncateUserAgent keeps stored user agents to a sane length
+func truncateUserAgent(ua string) string {
+	if len(ua) <= maxUserAgentLength {
+		return ua
+	}
+	return strings.ToValidUTF8(ua[:maxUserAgentLength], "")
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"free_toilet_map/toilet/token"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RefreshTokenTTL is how long a refresh token can be used. Every refresh
+// issues a new one, so an active session never expires.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// Login checks the user's credentials and starts a new session, or issues a
+// challenge if the user has 2FA enabled. Unknown users and wrong passwords
+// fail the same way, and repeated failures lock the username and the client
+// IP for a while. With 2FA the failures are only cleared once the second
+// factor is correct too, see CompleteLogin.
+func (s *Service) Login(username, password string, client ClientInfo) (models.LoginResult, error) {
+	keys := loginThrottleKeys(username, client.IP)
+	if err := s.checkLoginLocks(keys); err != nil {
+		return models.LoginResult{}, err
+	}
+
+	user, err := s.Repo.GetUserByUsername(username)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return models.LoginResult{}, err
+	}
+
+	hash := dummyPasswordHash()
+	if err == nil {
+		hash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || err != nil {
+		if err := s.recordLoginFailure(keys); err != nil {
+			return models.LoginResult{}, err
+		}
+		return models.LoginResult{}, auth.ErrInvalidCredentials
+	}
+
+	result, err := s.beginSession(user, client)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	if !result.MFARequired {
+		if err := s.Repo.ClearLoginFailures(repository.ThrottleAccount, normalizeLogin(username)); err != nil {
+			return models.LoginResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. The presented
+// refresh token is consumed; reusing it later revokes the whole session.
+func (s *Service) RefreshTokens(refreshToken string) (models.TokenPair, error) {
+	if refreshToken == "" {
+		return models.TokenPair{}, apperr.Invalid("refresh token is required")
+	}
+
+	newRefreshToken, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	userID, sessionID, err := s.Repo.RotateRefreshToken(
+		hashToken(refreshToken),
+		hashToken(newRefreshToken),
+		time.Now().UTC().Add(RefreshTokenTTL),
+	)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	// Reload the user so that role changes apply from the next refresh on
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return s.tokenPair(user, sessionID, newRefreshToken)
+}
+
+// Logout revokes the session the current access token belongs to
+func (s *Service) Logout(userID int, sessionID string) error {
+	return s.Repo.RevokeSession(sessionID, userID)
+}
+
+// LogoutAll revokes every session of the user, signing out all devices
+func (s *Service) LogoutAll(userID int) error {
+	return s.Repo.RevokeUserSessions(userID)
+}
+
+// Authenticate verifies an access token and checks that its session is
+// still active
+func (s *Service) Authenticate(accessToken string) (auth.Claims, error) {
+	claims, err := s.Tokens.ParseAccessToken(accessToken)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	active, err := s.Repo.IsSessionActive(claims.SessionID, claims.UserID)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if !active {
+		return auth.Claims{}, apperr.Unauthorized("session revoked")
+	}
+	if err := s.checkBanned(claims.UserID); err != nil {
+		return auth.Claims{}, err
+	}
+
+	if err := s.touchSession(claims.SessionID); err != nil {
+		log.Printf("could not update last use of session: %v", err)
+	}
+	return claims, nil
+}
+
+// PublicKeys returns the JWKS document other services use to verify our tokens
+func (s *Service) PublicKeys() token.JWKS {
+	return s.Tokens.JWKS()
+}
+
+func (s *Service) startSession(user models.User, client ClientInfo) (models.TokenPair, error) {
+	if err := s.checkBanned(user.ID); err != nil {
+		return models.TokenPair{}, s.withAppealToken(user.ID, err)
+	}
+
+	sessionID, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	expiresAt := time.Now().UTC().Add(RefreshTokenTTL)
+	if err := s.Repo.CreateSession(user.ID, sessionID, hashToken(refreshToken), expiresAt, truncateUserAgent(client.UserAgent), client.IP); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return s.tokenPair(user, sessionID, refreshToken)
+}
+
+// tokenPair signs an access token for a session. Users whose role requires
+// 2FA but who have not enabled it get the permissions of a regular user.
+func (s *Service) tokenPair(user models.User, sessionID, refreshToken string) (models.TokenPair, error) {
+	role := auth.Role(user.Role)
+	setupRequired := false
+	if mfaRequired(user.Role) {
+		enabled, err := s.Repo.IsTOTPEnabled(user.ID)
+		if err != nil {
+			return models.TokenPair{}, err
+		}
+		if !enabled {
+			role, setupRequired = auth.RoleUser, true
+		}
+	}
+
+	accessToken, err := s.Tokens.NewAccessToken(auth.Claims{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		Role:      role,
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{
+		Token:            accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(token.AccessTokenTTL.Seconds()),
+		RefreshExpiresIn: int(RefreshTokenTTL.Seconds()),
+		MFASetupRequired: setupRequired,
+	}, nil
+}
+
+// randomToken returns 32 random bytes encoded for use in URLs and JSON
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used to store refresh tokens; only their hash is persisted
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
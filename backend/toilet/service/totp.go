@@ -0,0 +1,274 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"free_toilet_map/toilet/totp"
+	"strings"
+	"time"
+)
+
+const (
+	// totpIssuer names the service in authenticator apps
+	totpIssuer = "Free Toilet Map"
+
+	loginChallengeTTL      = 5 * time.Minute
+	loginChallengeAttempts = 5
+	recoveryCodeCount      = 10
+)
+
+// mfaRequired reports whether users of a role must use two-factor
+// authentication. Moderators can delete other people's content, so their
+// accounts get the extra protection.
+func mfaRequired(role string) bool {
+	return auth.Role(role).AtLeast(auth.RoleModerator)
+}
+
+// GetTOTPStatus returns the user's two-factor authentication state
+func (s *Service) GetTOTPStatus(userID int) (models.TOTPStatus, error) {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.TOTPStatus{}, err
+	}
+	status := models.TOTPStatus{Required: mfaRequired(user.Role)}
+
+	if status.Enabled, err = s.Repo.IsTOTPEnabled(userID); err != nil {
+		return status, err
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.Repo.CountRecoveryCodes(userID); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// EnrollTOTP generates a new secret for the user. 2FA is not enabled until
+// the user confirms with a code from their authenticator app.
+func (s *Service) EnrollTOTP(userID int) (models.TOTPEnrollment, error) {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	if err := s.Repo.SaveTOTPSecret(userID, secret); err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+
+	return models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables 2FA once the user proves their app produces valid
+// codes, and returns the recovery codes
+func (s *Service) ConfirmTOTP(userID int, code string) (models.RecoveryCodes, error) {
+	secret, confirmed, _, err := s.Repo.GetTOTPSecret(userID)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if secret == "" || confirmed {
+		return models.RecoveryCodes{}, apperr.Conflict("no two-factor enrollment in progress")
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return models.RecoveryCodes{}, auth.ErrInvalidSecondFactor
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if err := s.Repo.ConfirmTOTP(userID, step, hashes, time.Now().UTC()); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return models.RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableTOTP turns 2FA off after checking a current code or a recovery
+// code. Users whose role requires 2FA cannot turn it off.
+func (s *Service) DisableTOTP(userID int, code string) error {
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if mfaRequired(user.Role) {
+		return apperr.Forbidden("two-factor authentication is required for your role")
+	}
+
+	if err := s.verifySecondFactor(userID, code); err != nil {
+		return err
+	}
+	return s.Repo.DeleteTOTP(userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking
+// a current code
+func (s *Service) RegenerateRecoveryCodes(userID int, code string) (models.RecoveryCodes, error) {
+	if err := s.verifySecondFactor(userID, code); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if err := s.Repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return models.RecoveryCodes{Codes: codes}, nil
+}
+
+// CompleteLogin finishes a login that is waiting for the second factor.
+// Each challenge allows a few attempts; after that the user has to enter
+// their password again. Wrong codes count against the login throttle like
+// wrong passwords, so that signing in again does not buy fresh guesses.
+func (s *Service) CompleteLogin(challengeToken, code string, client ClientInfo) (models.TokenPair, error) {
+	challengeHash := hashToken(challengeToken)
+	now := time.Now().UTC()
+
+	userID, err := s.Repo.AttemptLoginChallenge(challengeHash, loginChallengeAttempts, now)
+	if errors.Is(err, repository.ErrInvalidLoginChallenge) {
+		return models.TokenPair{}, auth.ErrLoginChallengeExpired
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	keys := loginThrottleKeys(user.Username, client.IP)
+	if err := s.checkLoginLocks(keys); err != nil {
+		return models.TokenPair{}, err
+	}
+	if err := s.verifySecondFactor(userID, code); err != nil {
+		if errors.Is(err, auth.ErrInvalidSecondFactor) {
+			if err := s.recordLoginFailure(keys); err != nil {
+				return models.TokenPair{}, err
+			}
+		}
+		return models.TokenPair{}, err
+	}
+	if err := s.Repo.DeleteLoginChallenge(challengeHash, now); err != nil {
+		return models.TokenPair{}, err
+	}
+	if err := s.Repo.ClearLoginFailures(repository.ThrottleAccount, normalizeLogin(user.Username)); err != nil {
+		return models.TokenPair{}, err
+	}
+	return s.startSession(user, client)
+}
+
+// beginSession starts a session for a user who passed the first factor, or
+// issues a login challenge if they have 2FA enabled
+func (s *Service) beginSession(user models.User, client ClientInfo) (models.LoginResult, error) {
+	enabled, err := s.Repo.IsTOTPEnabled(user.ID)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+
+	if !enabled {
+		tokens, err := s.startSession(user, client)
+		if err != nil {
+			return models.LoginResult{}, err
+		}
+		return models.LoginResult{TokenPair: &tokens}, nil
+	}
+
+	challenge, err := randomToken()
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	expiresAt := time.Now().UTC().Add(loginChallengeTTL)
+	if err := s.Repo.CreateLoginChallenge(hashToken(challenge), user.ID, expiresAt); err != nil {
+		return models.LoginResult{}, err
+	}
+
+	return models.LoginResult{
+		MFARequired:        true,
+		ChallengeToken:     challenge,
+		ChallengeExpiresIn: int(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// verifySecondFactor accepts a current TOTP code, which can be used only
+// once, or an unused recovery code
+func (s *Service) verifySecondFactor(userID int, code string) error {
+	secret, confirmed, lastStep, err := s.Repo.GetTOTPSecret(userID)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return apperr.Conflict("two-factor authentication is not enabled")
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		if step <= lastStep {
+			return auth.ErrInvalidSecondFactor
+		}
+		used, err := s.Repo.UseTOTPStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return auth.ErrInvalidSecondFactor
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return auth.ErrInvalidSecondFactor
+	}
+	used, err := s.Repo.UseRecoveryCode(userID, hashToken(normalized), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return auth.ErrInvalidSecondFactor
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns fresh recovery codes formatted for display
+// along with the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes = append(codes, raw[:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode drops the separators and case users may type a
+// recovery code with
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
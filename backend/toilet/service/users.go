@@ -0,0 +1,122 @@
+package service
+
+import (
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"free_toilet_map/toilet/repository"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxAvatarURLLength   = 2048
+)
+
+// languageTag accepts simple language tags like "ru", "en" or "en-GB"
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// GetUser retrieves a user by their ID
+func (s *Service) GetUser(userID int) (models.User, error) {
+	return s.Repo.GetUserByID(userID)
+}
+
+// UpdateProfile validates and applies a profile update, returning the updated user
+func (s *Service) UpdateProfile(userID int, update models.ProfileUpdate) (models.User, error) {
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return models.User{}, apperr.Invalid("display name is too long")
+		}
+		update.DisplayName = &name
+	}
+
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return models.User{}, apperr.Invalid("bio is too long")
+		}
+		update.Bio = &bio
+	}
+
+	if update.AvatarURL != nil && *update.AvatarURL != "" {
+		if len(*update.AvatarURL) > maxAvatarURLLength {
+			return models.User{}, apperr.Invalid("avatar url is too long")
+		}
+		u, err := url.Parse(*update.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return models.User{}, apperr.Invalid("avatar url must be an http(s) url")
+		}
+	}
+
+	if update.PreferredLanguage != nil && !languageTag.MatchString(*update.PreferredLanguage) {
+		return models.User{}, apperr.Invalid("invalid preferred language")
+	}
+
+	if err := s.Repo.UpdateUserProfile(userID, update); err != nil {
+		return models.User{}, err
+	}
+	return s.Repo.GetUserByID(userID)
+}
+
+// GetUserToilets retrieves the toilets added by a user
+func (s *Service) GetUserToilets(userID int) ([]models.Toilet, error) {
+	return s.Repo.GetToiletsByFounder(userID)
+}
+
+// GetUserReviews retrieves the reviews written by a user
+func (s *Service) GetUserReviews(userID int) ([]models.Review, error) {
+	return s.Repo.GetReviewsByUser(userID)
+}
+
+// GetPublicProfile builds the publicly visible profile of a user, as seen
+// by viewerID. Shadow-banned users only see their own profile; to everyone
+// else they do not exist.
+func (s *Service) GetPublicProfile(userID, viewerID int) (models.PublicProfile, error) {
+	if userID != viewerID {
+		banned, err := s.Repo.IsShadowBanned(userID)
+		if err != nil {
+			return models.PublicProfile{}, err
+		}
+		if banned {
+			return models.PublicProfile{}, repository.ErrUserNotFound
+		}
+	}
+
+	user, err := s.Repo.GetUserByID(userID)
+	if err != nil {
+		return models.PublicProfile{}, err
+	}
+
+	reviewsCount, err := s.Repo.CountReviewsByUser(userID)
+	if err != nil {
+		return models.PublicProfile{}, err
+	}
+
+	lists, err := s.Repo.GetPublicListsByUser(userID)
+	if err != nil {
+		return models.PublicProfile{}, err
+	}
+
+	rep, err := s.GetReputation(userID)
+	if err != nil {
+		return models.PublicProfile{}, err
+	}
+
+	return models.PublicProfile{
+		ID:           user.ID,
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		AvatarURL:    user.AvatarURL,
+		Bio:          user.Bio,
+		ToiletsFound: user.ToiletsFound,
+		ReviewsCount: reviewsCount,
+		Reputation:   rep.Score,
+		Level:        rep.Level,
+		CreatedAt:    user.CreatedAt,
+		Lists:        lists,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key is a single signing or verification key identified by its kid
+type Key struct {
+	ID        string
+	Algorithm string
+
+	signKey   interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey; nil for verify-only keys
+	verifyKey interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgHS256:
+		return jwt.SigningMethodHS256
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	default:
+		return jwt.SigningMethodEdDSA
+	}
+}
+
+// KeyConfig describes one key in the keys file. Secrets and PEM files are
+// referenced by path so the file itself can be stored alongside the
+// deployment configuration.
+type KeyConfig struct {
+	ID         string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Secret     string `json:"secret,omitempty"`      // HS256 only
+	SecretFile string `json:"secret_file,omitempty"` // HS256 only
+	// PEM encoded PKCS#1/PKCS#8 private key. Keys that are only kept for
+	// verification during rotation may give a PKIX public key instead.
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// Config is the content of the keys file pointed to by JWT_KEYS_FILE
+type Config struct {
+	Issuer     string      `json:"issuer,omitempty"`
+	SigningKey string      `json:"signing_key"` // kid of the key new tokens are signed with
+	Keys       []KeyConfig `json:"keys"`
+}
+
+// LoadConfig reads a keys file
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("could not read keys file: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse keys file: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadKey turns a key description into a usable key
+func loadKey(kc KeyConfig) (*Key, error) {
+	if kc.ID == "" {
+		return nil, errors.New("key without kid")
+	}
+	key := &Key{ID: kc.ID, Algorithm: kc.Algorithm}
+
+	switch kc.Algorithm {
+	case AlgHS256:
+		secret := []byte(kc.Secret)
+		if kc.SecretFile != "" {
+			data, err := os.ReadFile(kc.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", kc.ID, err)
+			}
+			secret = trimNewline(data)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("key %s: HS256 secret must be at least 32 bytes", kc.ID)
+		}
+		key.signKey, key.verifyKey = secret, secret
+
+	case AlgRS256, AlgEdDSA:
+		if kc.PrivateKeyFile != "" {
+			priv, err := readPrivateKey(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", kc.ID, err)
+			}
+			key.signKey = priv
+			key.verifyKey = priv.Public()
+		} else if kc.PublicKeyFile != "" {
+			pub, err := readPublicKey(kc.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", kc.ID, err)
+			}
+			key.verifyKey = pub
+		} else {
+			return nil, fmt.Errorf("key %s: private_key_file or public_key_file is required", kc.ID)
+		}
+
+		if err := checkKeyType(key); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("key %s: unsupported algorithm %q", kc.ID, kc.Algorithm)
+	}
+
+	return key, nil
+}
+
+// checkKeyType makes sure an asymmetric key matches its declared algorithm
+func checkKeyType(key *Key) error {
+	switch key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		if key.Algorithm == AlgRS256 {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if key.Algorithm == AlgEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("key %s: key type does not match algorithm %s", key.ID, key.Algorithm)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unsupported private key: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key type", path)
+	}
+	return signer, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unsupported public key: %w", path, err)
+	}
+	return key, nil
+}
+
+func trimNewline(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
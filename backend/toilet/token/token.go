@@ -0,0 +1,160 @@
+// Package token signs and verifies the access tokens issued by the service.
+// Keys are loaded from configuration and identified by a kid, so that old
+// keys can keep verifying tokens while a new key is rolled out.
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"free_toilet_map/toilet/auth"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessTokenTTL is how long an access token is accepted. Clients renew
+// it with a refresh token, so it is kept short.
+const AccessTokenTTL = 15 * time.Minute
+
+// Service signs access tokens with the current signing key and verifies
+// them with any of the configured keys
+type Service struct {
+	issuer  string
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewService builds a token service from a key configuration
+func NewService(cfg Config) (*Service, error) {
+	s := &Service{issuer: cfg.Issuer, keys: map[string]*Key{}}
+
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := s.keys[key.ID]; dup {
+			return nil, fmt.Errorf("duplicate kid %q", key.ID)
+		}
+		s.keys[key.ID] = key
+	}
+
+	signing, ok := s.keys[cfg.SigningKey]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", cfg.SigningKey)
+	}
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", cfg.SigningKey)
+	}
+	s.signing = signing
+
+	return s, nil
+}
+
+// NewServiceFromEnv configures the token service from the environment:
+//
+//   - JWT_KEYS_FILE points to a JSON keys file (see Config), or
+//   - JWT_SECRET sets a single HS256 key with kid "default".
+//
+// Without either, an ephemeral Ed25519 key is generated. Tokens signed with
+// it do not survive a restart and are not accepted by other instances, so
+// this is only suitable for development.
+func NewServiceFromEnv() (*Service, error) {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewService(cfg)
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return NewService(Config{
+			Issuer:     os.Getenv("JWT_ISSUER"),
+			SigningKey: "default",
+			Keys:       []KeyConfig{{ID: "default", Algorithm: AlgHS256, Secret: secret}},
+		})
+	}
+
+	log.Println("WARNING: neither JWT_KEYS_FILE nor JWT_SECRET is set, using an ephemeral signing key")
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{ID: "ephemeral", Algorithm: AlgEdDSA, signKey: priv, verifyKey: priv.Public()}
+	return &Service{
+		issuer:  os.Getenv("JWT_ISSUER"),
+		signing: key,
+		keys:    map[string]*Key{key.ID: key},
+	}, nil
+}
+
+// NewAccessToken signs an access token for a user's session
+func (s *Service) NewAccessToken(claims auth.Claims) (string, error) {
+	mapClaims := jwt.MapClaims{
+		"user_id": claims.UserID,
+		"sid":     claims.SessionID,
+		"role":    string(claims.Role),
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(AccessTokenTTL).Unix(),
+	}
+	if s.issuer != "" {
+		mapClaims["iss"] = s.issuer
+	}
+
+	token := jwt.NewWithClaims(s.signing.method(), mapClaims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.signKey)
+}
+
+// ParseAccessToken verifies an access token and returns its claims. The key
+// is selected by the token's kid and must match the token's algorithm.
+func (s *Service) ParseAccessToken(tokenStr string) (auth.Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA})}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+
+	token, err := jwt.Parse(tokenStr, s.keyFunc, opts...)
+	if err != nil || !token.Valid {
+		return auth.Claims{}, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return auth.Claims{}, errors.New("invalid token claims")
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return auth.Claims{}, errors.New("invalid user_id")
+	}
+
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return auth.Claims{}, errors.New("invalid session")
+	}
+
+	role := auth.RoleUser
+	if r, ok := claims["role"].(string); ok && auth.ValidRole(auth.Role(r)) {
+		role = auth.Role(r)
+	}
+
+	return auth.Claims{UserID: int(userID), SessionID: sessionID, Role: role}, nil
+}
+
+func (s *Service) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("algorithm %s does not match key %s", t.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"free_toilet_map/toilet/endpoint"
+	"net/http"
+)
+
+func decodeExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoint.ExportRequest{Format: r.URL.Query().Get("format")}, nil
+}
+
+// encodeExportResponse sends a personal data export as a download: a single
+// JSON document, or a ZIP archive with one JSON file per kind of data
+func encodeExportResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(endpoint.ExportResponse)
+	if !ok {
+		return errors.New("invalid export response")
+	}
+
+	// The username may hold characters that do not belong in a header, the
+	// ID is plain ASCII
+	name := fmt.Sprintf("free-toilet-map-%d-%s", resp.Data.Profile.ID, resp.Data.ExportedAt.Format("20060102"))
+
+	if resp.Format != "zip" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, name))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp.Data)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", resp.Data.Profile},
+		{"toilets.json", resp.Data.Toilets},
+		{"reviews.json", resp.Data.Reviews},
+		{"comments.json", resp.Data.Comments},
+		{"lists.json", resp.Data.Lists},
+		{"identities.json", resp.Data.Identities},
+		{"api_keys.json", resp.Data.APIKeys},
+		{"ownerships.json", resp.Data.Ownerships},
+		{"sessions.json", resp.Data.Sessions},
+		{"two_factor.json", resp.Data.TwoFactor},
+		{"reports.json", resp.Data.Reports},
+		{"warnings.json", resp.Data.Warnings},
+		{"bans.json", resp.Data.Bans},
+		{"appeals.json", resp.Data.Appeals},
+		{"audit_log.json", resp.Data.AuditLog},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: resp.Data.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/endpoint"
+	"net/http"
+	"strconv"
+)
+
+// Decode ?limit=&offset= of the list of pending toilets
+func decodePendingToiletsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	limit, offset, err := decodePage(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoint.PendingToiletsRequest{Limit: limit, Offset: offset}, nil
+}
+
+// decodePage reads the ?limit= and ?offset= of a paged list
+func decodePage(r *http.Request) (limit, offset int, err error) {
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return 0, 0, apperr.Invalid("invalid limit")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			return 0, 0, apperr.Invalid("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
+
+func decodeJSONApproval(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.ApprovalRequest
+	return decode(r, &req)
+}
@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Decode audit log filters from the query string. Times are RFC 3339.
+func decodeAuditLogRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+		RequestID:  q.Get("request_id"),
+	}
+
+	var err error
+	if v := q.Get("actor"); v != "" {
+		if filter.ActorID, err = strconv.Atoi(v); err != nil {
+			return nil, apperr.Invalid("invalid actor")
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperr.Invalid("invalid from time")
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperr.Invalid("invalid to time")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, apperr.Invalid("invalid limit")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, apperr.Invalid("invalid offset")
+		}
+	}
+	return filter, nil
+}
@@ -1,47 +1,124 @@
 package transport
 
 import (
+	"context"
+	"errors"
+	"free_toilet_map/toilet/apperr"
 	"free_toilet_map/toilet/auth"
 	"net/http"
-	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware checks for a valid JWT token and adds the user_id to the context
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenStr := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+// Authenticator verifies the credentials presented with a request and
+// returns the identity they carry. Tokens of sessions that were logged out
+// or revoked must be rejected.
+type Authenticator interface {
+	Authenticate(accessToken string) (auth.Claims, error)
+	AuthenticateAPIKey(key string, scope auth.Scope) (auth.Claims, error)
+}
+
+// AuthMiddleware checks for a valid JWT token bound to an active session and
+// adds the user_id, session id and role to the context. The token is taken
+// from the Authorization header or from the session cookie; cookie requests
+// that change state must carry the CSRF token. Suspended and banned users
+// are refused with 403 and the reason.
+func AuthMiddleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr, fromCookie := accessToken(r)
+			if fromCookie && !validCSRF(r) {
+				writeError(w, r, apperr.Forbidden("invalid CSRF token"))
+				return
+			}
+
+			// Verify the JWT token and its session
+			claims, err := authenticator.Authenticate(tokenStr)
+			if errors.Is(err, auth.ErrAccountBanned) {
+				writeError(w, r, err)
+				return
+			}
+			if err != nil {
+				writeError(w, r, apperr.Unauthorized("unauthorized"))
+				return
+			}
 
-		// Parse the JWT token
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			// Use your secret key or public key for signature verification
-			return []byte("secret_key"), nil
+			// Pass the context to the next handler
+			next.ServeHTTP(w, r.WithContext(withClaims(r, claims)))
 		})
+	}
+}
+
+// OptionalAuthMiddleware adds the identity of a valid JWT to the context
+// like AuthMiddleware, but lets requests without one, or with an invalid
+// one, through anonymously. It is used on public routes whose answer
+// depends on who asks.
+func OptionalAuthMiddleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr, _ := accessToken(r)
+			if tokenStr == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			claims, err := authenticator.Authenticate(tokenStr)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withClaims(r, claims)))
+		})
+	}
+}
+
+// APIKeyMiddleware accepts an X-API-Key with the given scope as an
+// alternative to the JWT. Without a key the request falls back to the JWT
+// check when required is set, or passes through with an optional JWT
+// otherwise, so public routes keep working while partners are held to
+// their quota.
+func APIKeyMiddleware(authenticator Authenticator, scope auth.Scope, required bool) func(http.Handler) http.Handler {
+	jwtAuth := AuthMiddleware(authenticator)
+	optionalJWT := OptionalAuthMiddleware(authenticator)
+
+	return func(next http.Handler) http.Handler {
+		withJWT := jwtAuth(next)
+		withOptionalJWT := optionalJWT(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				if required {
+					withJWT.ServeHTTP(w, r)
+				} else {
+					withOptionalJWT.ServeHTTP(w, r)
+				}
+				return
+			}
+
+			claims, err := authenticator.AuthenticateAPIKey(key, scope)
+			switch {
+			case errors.Is(err, auth.ErrAccountBanned),
+				errors.Is(err, auth.ErrQuotaExceeded),
+				errors.Is(err, auth.ErrInsufficientScope):
+				writeError(w, r, err)
+				return
+			case err != nil:
+				writeError(w, r, apperr.Unauthorized("unauthorized"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r, claims)))
+		})
+	}
+}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract user_id from claims
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			http.Error(w, "Invalid user_id", http.StatusUnauthorized)
-			return
-		}
-
-		// Add user_id to the context
-		ctx := auth.WithUserID(r.Context(), int(userID))
-
-		// Pass the context to the next handler
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// withClaims adds the authenticated identity to the request context
+func withClaims(r *http.Request, claims auth.Claims) context.Context {
+	ctx := auth.WithUserID(r.Context(), claims.UserID)
+	ctx = auth.WithRole(ctx, claims.Role)
+	if claims.SessionID != "" {
+		ctx = auth.WithSessionID(ctx, claims.SessionID)
+	}
+	if claims.APIKeyID != 0 {
+		ctx = auth.WithAPIKeyID(ctx, claims.APIKeyID)
+	}
+	return ctx
 }
@@ -0,0 +1,10 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+)
+
+func decodeChallengeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return r.URL.Query().Get("purpose"), nil
+}
@@ -0,0 +1,19 @@
+package transport
+
+import (
+	"context"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func decodeJSONComment(_ context.Context, r *http.Request) (interface{}, error) {
+	var comment models.Comment
+	return decode(r, &comment)
+}
+
+// Decode review ID from URL
+func decodeReviewID(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["reviewID"], nil
+}
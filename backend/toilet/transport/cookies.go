@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Cookie session mode. Browser clients that send "X-Session-Mode: cookie"
+// to /login, /login/2fa and /token/refresh get their tokens as HttpOnly
+// cookies instead of in the response body, out of reach of scripts.
+// Requests authenticated with the cookie must repeat the CSRF cookie in the
+// X-CSRF-Token header (double submit) unless they are safe methods.
+const (
+	sessionCookie     = "ftm_session"
+	refreshCookie     = "ftm_refresh"
+	csrfCookie        = "ftm_csrf"
+	csrfHeader        = "X-CSRF-Token"
+	sessionModeHeader = "X-Session-Mode"
+)
+
+var (
+	// COOKIE_SECURE=false allows the cookies over plain HTTP in development
+	secureCookies = os.Getenv("COOKIE_SECURE") != "false"
+	// COOKIE_SAMESITE=none is needed when the frontend is served from
+	// another site than the API
+	sameSite = parseSameSite(os.Getenv("COOKIE_SAMESITE"))
+)
+
+func parseSameSite(v string) http.SameSite {
+	switch strings.ToLower(v) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// csrfError is answered with 403 by encodeError
+type csrfError struct{}
+
+func (csrfError) Error() string   { return "invalid CSRF token" }
+func (csrfError) StatusCode() int { return http.StatusForbidden }
+
+var errInvalidCSRF error = csrfError{}
+
+type cookieModeKey struct{}
+
+// withSessionMode is a ServerBefore function that records whether the
+// client asked for cookie sessions
+func withSessionMode(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, cookieModeKey{}, strings.EqualFold(r.Header.Get(sessionModeHeader), "cookie"))
+}
+
+func cookieMode(ctx context.Context) bool {
+	on, _ := ctx.Value(cookieModeKey{}).(bool)
+	return on
+}
+
+// accessToken returns the bearer token of a request, or the session cookie
+// if there is no Authorization header
+func accessToken(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return strings.TrimPrefix(header, "Bearer "), false
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value, true
+	}
+	return "", false
+}
+
+// validCSRF checks the double-submitted CSRF token. Safe methods do not
+// change state and need no token.
+func validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	c, err := r.Cookie(csrfCookie)
+	header := r.Header.Get(csrfHeader)
+	if err != nil || c.Value == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(header)) == 1
+}
+
+// decodeRefreshRequest takes the refresh token from the body, or in cookie
+// mode from the refresh cookie
+func decodeRefreshRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if !cookieMode(ctx) {
+		return decodeJSONRequest(ctx, r)
+	}
+
+	if !validCSRF(r) {
+		return nil, errInvalidCSRF
+	}
+	req := map[string]string{}
+	if c, err := r.Cookie(refreshCookie); err == nil {
+		req["refresh_token"] = c.Value
+	}
+	return &req, nil
+}
+
+// encodeSessionResponse writes the result of a login or refresh. In cookie
+// mode the tokens are set as cookies and only their lifetimes and the CSRF
+// token are returned.
+func encodeSessionResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	var pair *models.TokenPair
+	switch resp := response.(type) {
+	case models.TokenPair:
+		pair = &resp
+	case models.LoginResult:
+		pair = resp.TokenPair
+	}
+	if pair == nil || !cookieMode(ctx) {
+		return encodeResponse(ctx, w, response)
+	}
+
+	csrf, err := setSessionCookies(w, pair)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"expires_in":         pair.ExpiresIn,
+		"refresh_expires_in": pair.RefreshExpiresIn,
+		"mfa_setup_required": pair.MFASetupRequired,
+		"csrf_token":         csrf,
+	})
+}
+
+// setSessionCookies sets the tokens of a session and a new CSRF token as
+// cookies and returns the CSRF token
+func setSessionCookies(w http.ResponseWriter, pair *models.TokenPair) (string, error) {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+	setCookie(w, sessionCookie, pair.Token, "/", pair.ExpiresIn, true)
+	setCookie(w, refreshCookie, pair.RefreshToken, "/token/refresh", pair.RefreshExpiresIn, true)
+	setCookie(w, csrfCookie, csrf, "/", pair.RefreshExpiresIn, false)
+	return csrf, nil
+}
+
+// encodeLogoutResponse clears the session cookies, if any were set
+func encodeLogoutResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	setCookie(w, sessionCookie, "", "/", -1, true)
+	setCookie(w, refreshCookie, "", "/token/refresh", -1, true)
+	setCookie(w, csrfCookie, "", "/", -1, false)
+	return encodeResponse(ctx, w, response)
+}
+
+func setCookie(w http.ResponseWriter, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   secureCookies || sameSite == http.SameSiteNoneMode,
+		SameSite: sameSite,
+	})
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("could not generate CSRF token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
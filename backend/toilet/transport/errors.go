@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"free_toilet_map/toilet/auth"
+	"log"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// newServer creates a go-kit server that answers errors with encodeError
+func newServer(e endpoint.Endpoint, dec httptransport.DecodeRequestFunc, enc httptransport.EncodeResponseFunc, options ...httptransport.ServerOption) *httptransport.Server {
+	options = append([]httptransport.ServerOption{
+		httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		httptransport.ServerErrorEncoder(encodeError),
+	}, options...)
+	return httptransport.NewServer(e, dec, enc, options...)
+}
+
+// encodeError answers a failed request with a problem document (RFC 9457).
+// The status comes from the error's StatusCode, see apperr; errors without
+// one are internal errors, which are logged and answered without details.
+// Details an error encodes itself, like the fields of validation errors,
+// are added as extension members.
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	status := http.StatusInternalServerError
+	var coder httptransport.StatusCoder
+	if errors.As(err, &coder) {
+		status = coder.StatusCode()
+	}
+	if status >= http.StatusInternalServerError {
+		log.Printf("request %s failed: %v", auth.GetRequestID(ctx), err)
+		writeProblem(ctx, w, status, "internal server error", nil)
+		return
+	}
+
+	members := map[string]interface{}{}
+	var marshaler json.Marshaler
+	if errors.As(err, &marshaler) {
+		if data, err := marshaler.MarshalJSON(); err == nil {
+			json.Unmarshal(data, &members)
+		}
+	}
+	var headerer httptransport.Headerer
+	if errors.As(err, &headerer) {
+		for name, values := range headerer.Headers() {
+			for _, v := range values {
+				w.Header().Add(name, v)
+			}
+		}
+	}
+	writeProblem(ctx, w, status, err.Error(), members)
+}
+
+// writeError answers a request that failed before it reached an endpoint,
+// like in the authentication middleware, see encodeError
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	encodeError(httptransport.PopulateRequestContext(r.Context(), r), err, w)
+}
+
+// writeProblem writes a problem document with the standard members and
+// the given extension members. The message is repeated as "error", which
+// is where clients looked for it before.
+func writeProblem(ctx context.Context, w http.ResponseWriter, status int, detail string, members map[string]interface{}) {
+	if members == nil {
+		members = map[string]interface{}{}
+	}
+	members["type"] = "about:blank"
+	members["title"] = http.StatusText(status)
+	members["status"] = status
+	members["detail"] = detail
+	if path, ok := ctx.Value(httptransport.ContextKeyRequestPath).(string); ok {
+		members["instance"] = path
+	}
+	if _, ok := members["error"]; !ok {
+		members["error"] = detail
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(members)
+}
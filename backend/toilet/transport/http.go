@@ -3,11 +3,14 @@ package transport
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/auth"
 	"free_toilet_map/toilet/endpoint"
 	models "free_toilet_map/toilet/model"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
@@ -16,9 +19,18 @@ import (
 // CORS middleware to handle cross-origin requests
 func withCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		// Cookie sessions need credentials, which browsers only send to an
+		// explicitly allowed origin. Everyone else gets the public API.
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && allowedOrigins[origin] {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-CSRF-Token, X-Session-Mode, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -29,12 +41,29 @@ func withCORS(h http.Handler) http.Handler {
 	})
 }
 
+// allowedOrigins are the frontends that may use cookie sessions, from
+// CORS_ALLOWED_ORIGINS (comma separated) or else APP_URL
+var allowedOrigins = parseOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"), os.Getenv("APP_URL"))
+
+func parseOrigins(list, fallback string) map[string]bool {
+	if list == "" {
+		list = fallback
+	}
+	origins := map[string]bool{}
+	for _, o := range strings.Split(list, ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" && o != "*" {
+			origins[o] = true
+		}
+	}
+	return origins
+}
+
 // MethodOnly ensures the correct HTTP method is used
 func methodOnly(method string, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Println("Incoming:", r.Method, r.URL.Path)
 		if r.Method != method {
-			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			writeProblem(r.Context(), w, http.StatusMethodNotAllowed, "method not allowed", map[string]interface{}{"instance": r.URL.Path})
 			return
 		}
 		h.ServeHTTP(w, r)
@@ -42,59 +71,576 @@ func methodOnly(method string, h http.Handler) http.Handler {
 }
 
 // NewHTTPHandler creates and returns the HTTP handler for the application
-func NewHTTPHandler(e endpoint.Endpoints) http.Handler {
+func NewHTTPHandler(e endpoint.Endpoints, authenticator Authenticator) http.Handler {
 	mux := mux.NewRouter()
+	requireAuth := AuthMiddleware(authenticator)
+	optionalAuth := OptionalAuthMiddleware(authenticator)
+	withAPIKey := func(scope auth.Scope, required bool) func(http.Handler) http.Handler {
+		return APIKeyMiddleware(authenticator, scope, required)
+	}
 
 	// User creation route
-	mux.Handle("/user/create", methodOnly("POST", httptransport.NewServer(
+	mux.Handle("/user/create", methodOnly("POST", newServer(
 		e.CreateUser,
 		decodeJSONRequest,
 		encodeResponse,
 	)))
 
+	// Proof-of-work challenge to solve before registering or signing in,
+	// ?purpose=register or ?purpose=login
+	mux.Handle("/challenge", methodOnly("GET", newServer(
+		e.NewChallenge,
+		decodeChallengeRequest,
+		encodeResponse,
+	)))
+
 	// Login route
-	mux.Handle("/login", httptransport.NewServer(
+	mux.Handle("/login", newServer(
 		e.Login,
 		decodeJSONRequest,
-		encodeResponse,
+		encodeSessionResponse,
+		httptransport.ServerBefore(withSessionMode),
 	))
 
-	// Toilets listing route
-	mux.Handle("/toilets", httptransport.NewServer(
+	// Second login step for users with two-factor authentication
+	mux.Handle("/login/2fa", methodOnly("POST", newServer(
+		e.CompleteLogin,
+		decodeJSONRequest,
+		encodeSessionResponse,
+		httptransport.ServerBefore(withSessionMode),
+	)))
+
+	// Two-factor authentication settings (requires authentication)
+	mux.Handle("/me/2fa", methodOnly("GET", requireAuth(newServer(
+		e.GetTOTPStatus,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/2fa/enroll", methodOnly("POST", requireAuth(newServer(
+		e.EnrollTOTP,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/2fa/confirm", methodOnly("POST", requireAuth(newServer(
+		e.ConfirmTOTP,
+		decodeJSONRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/2fa/disable", methodOnly("POST", requireAuth(newServer(
+		e.DisableTOTP,
+		decodeJSONRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/2fa/recovery-codes", methodOnly("POST", requireAuth(newServer(
+		e.RegenRecoveryCodes,
+		decodeJSONRequest,
+		encodeResponse,
+	))))
+
+	// "Sign in with..." through OpenID Connect providers
+	mux.Handle("/oidc/providers", methodOnly("GET", newServer(
+		e.ExternalProviders,
+		decodeEmptyRequest,
+		encodeResponse,
+	)))
+
+	mux.Handle("/oidc/{provider}/login", methodOnly("GET", newServer(
+		e.ExternalLogin,
+		decodeExternalLoginRequest,
+		encodeRedirect,
+	)))
+
+	mux.Handle("/oidc/{provider}/callback", methodOnly("GET", newServer(
+		e.ExternalCallback,
+		decodeExternalLoginRequest,
+		encodeCallbackRedirect,
+	)))
+
+	mux.Handle("/oidc/{provider}/link", methodOnly("POST", requireAuth(newServer(
+		e.LinkExternal,
+		decodeExternalLoginRequest,
+		encodeExternalAuthURL,
+	))))
+
+	// Signing in again through a linked provider, to confirm who the user
+	// is where a password would be asked for
+	mux.Handle("/oidc/{provider}/reauth", methodOnly("POST", requireAuth(newServer(
+		e.ReauthExternal,
+		decodeExternalLoginRequest,
+		encodeExternalAuthURL,
+	))))
+
+	// External accounts linked to the current user (requires authentication)
+	mux.Handle("/me/identities", methodOnly("GET", requireAuth(newServer(
+		e.GetMyIdentities,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/identities/unlink", methodOnly("POST", requireAuth(newServer(
+		e.UnlinkIdentity,
+		decodeJSONRequest,
+		encodeResponse,
+	))))
+
+	// Password reset by email
+	mux.Handle("/password/forgot", methodOnly("POST", newServer(
+		e.ForgotPassword,
+		decodeJSONRequest,
+		encodeResponse,
+	)))
+
+	mux.Handle("/password/reset", methodOnly("POST", newServer(
+		e.ResetPassword,
+		decodeJSONRequest,
+		encodeResponse,
+	)))
+
+	// Email verification
+	mux.Handle("/email/verify", methodOnly("POST", newServer(
+		e.VerifyEmail,
+		decodeJSONRequest,
+		encodeResponse,
+	)))
+
+	mux.Handle("/email/verify/resend", methodOnly("POST", requireAuth(newServer(
+		e.ResendVerification,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	// Exchange a refresh token for a new token pair
+	mux.Handle("/token/refresh", methodOnly("POST", newServer(
+		e.RefreshToken,
+		decodeRefreshRequest,
+		encodeSessionResponse,
+		httptransport.ServerBefore(withSessionMode),
+	)))
+
+	// Public keys for verifying our access tokens
+	mux.Handle("/.well-known/jwks.json", methodOnly("GET", newServer(
+		e.JWKS,
+		decodeEmptyRequest,
+		encodeResponse,
+	)))
+
+	// Revoke the current session (requires authentication)
+	mux.Handle("/logout", methodOnly("POST", requireAuth(newServer(
+		e.Logout,
+		decodeEmptyRequest,
+		encodeLogoutResponse,
+	))))
+
+	// Signed-in devices of the current user (requires authentication)
+	mux.Handle("/me/sessions", methodOnly("GET", requireAuth(newServer(
+		e.GetMySessions,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/sessions/{sessionID}", requireAuth(newServer(
+		e.RevokeSession,
+		decodeSessionID,
+		encodeResponse,
+	))).Methods("DELETE")
+
+	// Revoke all sessions of the current user (requires authentication)
+	mux.Handle("/logout/all", methodOnly("POST", requireAuth(newServer(
+		e.LogoutAll,
+		decodeEmptyRequest,
+		encodeLogoutResponse,
+	))))
+
+	// Toilets listing route (public, API keys need toilets:read)
+	mux.Handle("/toilets", withAPIKey(auth.ScopeToiletsRead, false)(newServer(
 		e.ListToilets,
 		func(_ context.Context, r *http.Request) (interface{}, error) { return nil, nil },
 		encodeResponse,
-	))
+	)))
 
-	// Add toilet (requires authentication)
-	mux.Handle("/toilet/add", AuthMiddleware(httptransport.NewServer(
+	// Add toilet (requires authentication or an API key with toilets:write)
+	mux.Handle("/toilet/add", withAPIKey(auth.ScopeToiletsWrite, true)(newServer(
 		e.AddToilet,
 		decodeJSONToilet,
 		encodeResponse,
 	)))
 
-	// Add review (requires authentication)
-	mux.Handle("/review/add", AuthMiddleware(httptransport.NewServer(
+	// Add review (requires authentication or an API key with reviews:write)
+	mux.Handle("/review/add", withAPIKey(auth.ScopeReviewsWrite, true)(newServer(
 		e.AddReview,
 		decodeJSONReview,
 		encodeResponse,
 	)))
 
-	// Get reviews by toilet ID
-	mux.Handle("/toilet/{toiletID}/reviews", methodOnly("GET", httptransport.NewServer(
+	// Get reviews by toilet ID (public, API keys need reviews:read)
+	mux.Handle("/toilet/{toiletID}/reviews", methodOnly("GET", withAPIKey(auth.ScopeReviewsRead, false)(newServer(
 		e.GetReviewsByToilet,
 		decodeJSONToiletID,
 		encodeResponse,
-	)))
+	))))
 
 	// Delete toilet (requires authentication)
-	mux.Handle("/toilet/delete", AuthMiddleware(httptransport.NewServer(
+	mux.Handle("/toilet/delete", requireAuth(newServer(
 		e.DeleteToilet,
 		decodeJSONDeleteToilet,
 		encodeResponse,
 	)))
 
-	return withCORS(mux) // Apply CORS middleware
+	// Current user profile (requires authentication)
+	mux.Handle("/me", requireAuth(newServer(
+		e.GetMe,
+		decodeEmptyRequest,
+		encodeResponse,
+	))).Methods("GET")
+
+	mux.Handle("/me", requireAuth(newServer(
+		e.UpdateMe,
+		decodeJSONProfileUpdate,
+		encodeResponse,
+	))).Methods("PATCH")
+
+	// Account deletion, confirmed with the password (requires authentication)
+	mux.Handle("/me", requireAuth(newServer(
+		e.DeleteMe,
+		decodeJSONRequest,
+		encodeResponse,
+	))).Methods("DELETE")
+
+	// Reputation score with its factors (requires authentication)
+	mux.Handle("/me/reputation", methodOnly("GET", requireAuth(newServer(
+		e.GetMyReputation,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	// Personal data export, ?format=zip for an archive (requires authentication)
+	mux.Handle("/me/export", methodOnly("GET", requireAuth(newServer(
+		e.ExportMe,
+		decodeExportRequest,
+		encodeExportResponse,
+	))))
+
+	// Contributions of the current user (requires authentication)
+	mux.Handle("/me/toilets", methodOnly("GET", requireAuth(newServer(
+		e.GetMyToilets,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/reviews", methodOnly("GET", requireAuth(newServer(
+		e.GetMyReviews,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	// Public user profile
+	mux.Handle("/user/{userID:[0-9]+}", methodOnly("GET", optionalAuth(newServer(
+		e.GetUserProfile,
+		decodeUserID,
+		encodeResponse,
+	))))
+
+	// Saved lists of the current user (requires authentication)
+	mux.Handle("/me/lists", methodOnly("GET", requireAuth(newServer(
+		e.GetMyLists,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/create", methodOnly("POST", requireAuth(newServer(
+		e.CreateList,
+		decodeJSONList,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/update", methodOnly("POST", requireAuth(newServer(
+		e.UpdateList,
+		decodeJSONList,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/delete", methodOnly("POST", requireAuth(newServer(
+		e.DeleteList,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/item/add", methodOnly("POST", requireAuth(newServer(
+		e.AddListItem,
+		decodeJSONListItem,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/item/update", methodOnly("POST", requireAuth(newServer(
+		e.UpdateListItem,
+		decodeJSONListItem,
+		encodeResponse,
+	))))
+
+	mux.Handle("/list/item/remove", methodOnly("POST", requireAuth(newServer(
+		e.RemoveListItem,
+		decodeJSONListItem,
+		encodeResponse,
+	))))
+
+	// Shared (unlisted or public) list by slug, ?format=geojson for GeoJSON
+	mux.Handle("/list/shared/{slug}", methodOnly("GET", optionalAuth(newServer(
+		e.GetSharedList,
+		decodeSharedListRequest,
+		encodeSharedListResponse,
+	))))
+
+	// Edit a toilet (founder or moderator)
+	mux.Handle("/toilet/update", methodOnly("POST", requireAuth(newServer(
+		e.UpdateToilet,
+		decodeJSONToilet,
+		encodeResponse,
+	))))
+
+	// Edit or delete a review (author or moderator)
+	mux.Handle("/review/update", methodOnly("POST", requireAuth(newServer(
+		e.UpdateReview,
+		decodeJSONReview,
+		encodeResponse,
+	))))
+
+	mux.Handle("/review/delete", methodOnly("POST", requireAuth(newServer(
+		e.DeleteReview,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	// Comments under reviews
+	mux.Handle("/review/{reviewID:[0-9]+}/comments", methodOnly("GET", optionalAuth(newServer(
+		e.GetComments,
+		decodeReviewID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/comment/add", methodOnly("POST", requireAuth(newServer(
+		e.AddComment,
+		decodeJSONComment,
+		encodeResponse,
+	))))
+
+	mux.Handle("/comment/update", methodOnly("POST", requireAuth(newServer(
+		e.UpdateComment,
+		decodeJSONComment,
+		encodeResponse,
+	))))
+
+	mux.Handle("/comment/delete", methodOnly("POST", requireAuth(newServer(
+		e.DeleteComment,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	// Moderation of comments (moderators only)
+	mux.Handle("/comment/hide", methodOnly("POST", requireAuth(newServer(
+		e.HideComment,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/comment/restore", methodOnly("POST", requireAuth(newServer(
+		e.RestoreComment,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	// Hiding toilets and reviews (moderators only)
+	mux.Handle("/toilet/hide", methodOnly("POST", requireAuth(newServer(
+		e.HideToilet,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/toilet/restore", methodOnly("POST", requireAuth(newServer(
+		e.RestoreToilet,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/review/hide", methodOnly("POST", requireAuth(newServer(
+		e.HideReview,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/review/restore", methodOnly("POST", requireAuth(newServer(
+		e.RestoreReview,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	// Approval of new toilets: confirmations by users (requires
+	// authentication), decisions by moderators (moderators only)
+	mux.Handle("/toilet/confirm", methodOnly("POST", requireAuth(newServer(
+		e.ConfirmToilet,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/toilets/pending", methodOnly("GET", requireAuth(newServer(
+		e.PendingToilets,
+		decodePendingToiletsRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/toilet/approve", methodOnly("POST", requireAuth(newServer(
+		e.ApproveToilet,
+		decodeJSONApproval,
+		encodeResponse,
+	))))
+
+	mux.Handle("/toilet/reject", methodOnly("POST", requireAuth(newServer(
+		e.RejectToilet,
+		decodeJSONApproval,
+		encodeResponse,
+	))))
+
+	// Reporting toilets, reviews, comments and users (requires authentication)
+	mux.Handle("/report", methodOnly("POST", requireAuth(newServer(
+		e.Report,
+		decodeJSONReport,
+		encodeResponse,
+	))))
+
+	// Moderation queue (moderators only)
+	mux.Handle("/moderation/queue", methodOnly("GET", requireAuth(newServer(
+		e.ModerationQueue,
+		decodeModerationQueueRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/queue/{itemID:[0-9]+}", methodOnly("GET", requireAuth(newServer(
+		e.ModerationItem,
+		decodeModerationItemID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/queue/assign", methodOnly("POST", requireAuth(newServer(
+		e.AssignItem,
+		decodeJSONAssign,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/queue/note", methodOnly("POST", requireAuth(newServer(
+		e.AddItemNote,
+		decodeJSONNote,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/queue/resolve", methodOnly("POST", requireAuth(newServer(
+		e.ResolveItem,
+		decodeJSONResolution,
+		encodeResponse,
+	))))
+
+	// Verified owners of the venue of a toilet (moderators only)
+	mux.Handle("/moderation/owner/verify", methodOnly("POST", requireAuth(newServer(
+		e.VerifyOwner,
+		decodeJSONOwner,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/owner/revoke", methodOnly("POST", requireAuth(newServer(
+		e.RevokeOwner,
+		decodeJSONOwner,
+		encodeResponse,
+	))))
+
+	// Suspensions, bans and shadow bans (moderators only)
+	mux.Handle("/moderation/ban", methodOnly("POST", requireAuth(newServer(
+		e.BanUser,
+		decodeJSONBan,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/ban/lift", methodOnly("POST", requireAuth(newServer(
+		e.LiftBan,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/user/{userID:[0-9]+}/bans", methodOnly("GET", requireAuth(newServer(
+		e.UserBans,
+		decodeBansUserID,
+		encodeResponse,
+	))))
+
+	// Appeals against bans: filed by the banned user with their credentials,
+	// decided by moderators
+	mux.Handle("/appeal", methodOnly("POST", newServer(
+		e.AppealBan,
+		decodeJSONAppeal,
+		encodeResponse,
+	)))
+
+	mux.Handle("/moderation/appeals", methodOnly("GET", requireAuth(newServer(
+		e.Appeals,
+		decodeAppealsRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/moderation/appeals/decide", methodOnly("POST", requireAuth(newServer(
+		e.DecideAppeal,
+		decodeJSONDecideAppeal,
+		encodeResponse,
+	))))
+
+	// API keys for third-party apps (requires authentication)
+	mux.Handle("/me/apikeys", methodOnly("GET", requireAuth(newServer(
+		e.GetMyAPIKeys,
+		decodeEmptyRequest,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/apikeys/create", methodOnly("POST", requireAuth(newServer(
+		e.CreateAPIKey,
+		decodeJSONAPIKey,
+		encodeResponse,
+	))))
+
+	mux.Handle("/me/apikeys/revoke", methodOnly("POST", requireAuth(newServer(
+		e.RevokeAPIKey,
+		decodeJSONID,
+		encodeResponse,
+	))))
+
+	// API key quotas (admins only)
+	mux.Handle("/admin/apikey/quota", methodOnly("POST", requireAuth(newServer(
+		e.SetAPIKeyQuota,
+		decodeJSONAPIKey,
+		encodeResponse,
+	))))
+
+	// Role management (admins only)
+	mux.Handle("/admin/user/role", methodOnly("POST", requireAuth(newServer(
+		e.SetUserRole,
+		decodeJSONSetRole,
+		encodeResponse,
+	))))
+
+	// Lift a login lockout of a username or client IP (admins only)
+	mux.Handle("/admin/login/unlock", methodOnly("POST", requireAuth(newServer(
+		e.UnlockLogin,
+		decodeJSONRequest,
+		encodeResponse,
+	))))
+
+	// Audit log, filtered by ?actor=&action=&target_type=&target_id=&request_id=&from=&to= (admins only)
+	mux.Handle("/admin/audit", methodOnly("GET", requireAuth(newServer(
+		e.AuditLog,
+		decodeAuditLogRequest,
+		encodeResponse,
+	))))
+
+	return withRequestInfo(withCORS(mux)) // Apply request info and CORS middleware
 }
 
 // Decoding functions for different routes
@@ -120,8 +666,10 @@ func decodeJSONToilet(_ context.Context, r *http.Request) (interface{}, error) {
 
 func decode(r *http.Request, target interface{}) (interface{}, error) {
 	defer r.Body.Close()
-	err := json.NewDecoder(r.Body).Decode(target)
-	return target, err
+	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+		return target, apperr.Wrap(apperr.KindInvalid, "invalid request body", err)
+	}
+	return target, nil
 }
 
 // Encoding the response to JSON
@@ -152,5 +700,5 @@ func decodeJSONDeleteToilet(_ context.Context, r *http.Request) (interface{}, er
 	}
 
 	log.Println("Invalid or missing 'id' field in request")
-	return nil, errors.New("invalid or missing 'id' field") // Если id не существует или не верный формат
+	return nil, apperr.Invalid("invalid or missing 'id' field") // Если id не существует или не верный формат
 }
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/endpoint"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func decodeJSONList(_ context.Context, r *http.Request) (interface{}, error) {
+	var list models.ToiletList
+	return decode(r, &list)
+}
+
+func decodeJSONListItem(_ context.Context, r *http.Request) (interface{}, error) {
+	var item models.ListItem
+	return decode(r, &item)
+}
+
+// Decode a request body of the form {"id": 123}
+func decodeJSONID(_ context.Context, r *http.Request) (interface{}, error) {
+	var req map[string]interface{}
+	if _, err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	if id, ok := req["id"].(float64); ok {
+		return map[string]int{"id": int(id)}, nil
+	}
+	return nil, apperr.Invalid("invalid or missing 'id' field")
+}
+
+// Decode shared list slug from URL, the format is taken from ?format=
+func decodeSharedListRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoint.SharedListRequest{
+		Slug:   mux.Vars(r)["slug"],
+		Format: r.URL.Query().Get("format"),
+	}, nil
+}
+
+// Encode a shared list either as plain JSON or as GeoJSON
+func encodeSharedListResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	if _, ok := response.(models.FeatureCollection); ok {
+		w.Header().Set("Content-Type", "application/geo+json")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	return json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/apperr"
+	"free_toilet_map/toilet/endpoint"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func decodeJSONOwner(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.OwnerRequest
+	return decode(r, &req)
+}
+
+func decodeJSONReport(_ context.Context, r *http.Request) (interface{}, error) {
+	var report models.Report
+	return decode(r, &report)
+}
+
+// Decode queue filters from ?state=&target_type=&reason=&assignee=&limit=&offset=
+func decodeModerationQueueRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := endpoint.ModerationQueueRequest{
+		State:      q.Get("state"),
+		TargetType: q.Get("target_type"),
+		Reason:     q.Get("reason"),
+		Assignee:   q.Get("assignee"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, apperr.Invalid("invalid limit")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if req.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, apperr.Invalid("invalid offset")
+		}
+	}
+	return req, nil
+}
+
+// Decode moderation item ID from URL
+func decodeModerationItemID(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["itemID"], nil
+}
+
+func decodeJSONAssign(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.AssignRequest
+	return decode(r, &req)
+}
+
+func decodeJSONNote(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.NoteRequest
+	return decode(r, &req)
+}
+
+func decodeJSONResolution(_ context.Context, r *http.Request) (interface{}, error) {
+	var res models.Resolution
+	return decode(r, &res)
+}
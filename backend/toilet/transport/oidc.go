@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/endpoint"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcStateCookie keeps the state of a "Sign in with..." flow in the
+// browser that started it, see service.CompleteExternalLogin.
+// oidcSessionModeCookie remembers that the flow was started with
+// ?session=cookie or by a frontend with a cookie session, since the
+// callback is a navigation without our headers.
+const (
+	oidcStateCookie       = "ftm_oidc_state"
+	oidcSessionModeCookie = "ftm_oidc_session"
+)
+
+// Decode provider name from URL and the provider's callback parameters
+func decodeExternalLoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := endpoint.ExternalLoginRequest{
+		Provider:   mux.Vars(r)["provider"],
+		Code:       q.Get("code"),
+		State:      q.Get("state"),
+		Error:      q.Get("error"),
+		CookieMode: strings.EqualFold(q.Get("session"), "cookie"),
+	}
+	if c, err := r.Cookie(oidcStateCookie); err == nil {
+		req.BrowserState = c.Value
+	}
+	if c, err := r.Cookie(oidcSessionModeCookie); err == nil && c.Value == "cookie" {
+		req.CookieMode = true
+	}
+	// Links are started by a frontend that is signed in with the cookie
+	if _, fromCookie := accessToken(r); fromCookie {
+		req.CookieMode = true
+	}
+	return req, nil
+}
+
+// setOIDCStateCookie sets or, with an empty state, clears the state
+// cookie
+func setOIDCStateCookie(w http.ResponseWriter, state string) {
+	setOIDCCookie(w, oidcStateCookie, state)
+}
+
+// setOIDCCookie sets or, with an empty value, clears a cookie of the flow.
+// The provider sends the browser back with a top-level navigation from
+// its own site, which strict cookies do not survive, so it is lax unless
+// the cookies are configured to be sent cross-site anyway.
+func setOIDCCookie(w http.ResponseWriter, name, value string) {
+	maxAge := 0
+	if value == "" {
+		maxAge = -1
+	}
+	cookieSameSite := http.SameSiteLaxMode
+	if sameSite == http.SameSiteNoneMode {
+		cookieSameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secureCookies || cookieSameSite == http.SameSiteNoneMode,
+		SameSite: cookieSameSite,
+	})
+}
+
+// Encode the provider URL of a link request as JSON, with the cookies of
+// the flow
+func encodeExternalAuthURL(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if redirect, ok := response.(endpoint.Redirect); ok && redirect.State != "" {
+		setOIDCStateCookie(w, redirect.State)
+		if redirect.CookieMode {
+			setOIDCCookie(w, oidcSessionModeCookie, "cookie")
+		}
+	}
+	return encodeResponse(ctx, w, response)
+}
+
+// Encode the redirect of the provider's callback, dropping the cookies of
+// the flow, which have served their purpose. A session of a flow in cookie
+// mode is set as cookies, and the CSRF token is handed to the frontend in
+// the fragment.
+func encodeCallbackRedirect(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	setOIDCStateCookie(w, "")
+	setOIDCCookie(w, oidcSessionModeCookie, "")
+
+	if redirect, ok := response.(endpoint.Redirect); ok && redirect.Session != nil {
+		csrf, err := setSessionCookies(w, redirect.Session)
+		if err != nil {
+			return err
+		}
+		redirect.URL += "&" + url.Values{"csrf_token": {csrf}}.Encode()
+		response = redirect
+	}
+	return encodeRedirect(ctx, w, response)
+}
+
+// Encode an endpoint.Redirect as a 302 response
+func encodeRedirect(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	redirect, ok := response.(endpoint.Redirect)
+	if !ok {
+		return encodeResponse(context.Background(), w, response)
+	}
+	if redirect.State != "" {
+		setOIDCStateCookie(w, redirect.State)
+		if redirect.CookieMode {
+			setOIDCCookie(w, oidcSessionModeCookie, "cookie")
+		}
+	}
+	w.Header().Set("Location", redirect.URL)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusFound)
+	return nil
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"free_toilet_map/toilet/auth"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// trustedProxies is the number of reverse proxies in front of the service,
+// from TRUST_PROXY ("true" is one). Each of them appends the address it
+// got the request from to X-Forwarded-For, so the client is the entry
+// that many places from the right; whatever is left of it was sent by the
+// client and cannot be trusted. Only set it when the service is reachable
+// solely through the proxies, otherwise clients can pick their own address.
+var trustedProxies = parseTrustProxy(os.Getenv("TRUST_PROXY"))
+
+func parseTrustProxy(v string) int {
+	if v == "true" {
+		return 1
+	}
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// clientIP returns the address of the client that sent the request
+func clientIP(r *http.Request) string {
+	if trustedProxies > 0 {
+		if ip := forwardedFor(r.Header.Values("X-Forwarded-For"), trustedProxies); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor picks the address the outermost of hops proxies got the
+// request from. With fewer entries than proxies, all of them were added
+// by proxies and the leftmost one is the client.
+func forwardedFor(headers []string, hops int) string {
+	var entries []string
+	for _, h := range headers {
+		entries = append(entries, strings.Split(h, ",")...)
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	entry := entries[max(len(entries)-hops, 0)]
+	if ip := net.ParseIP(strings.TrimSpace(entry)); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// withRequestInfo records the request ID, client address and user agent in
+// the request context for the endpoints. The request ID is taken from a
+// well-formed X-Request-ID header, so that a proxy can correlate its logs
+// with ours, or generated, and sent back in the response.
+func withRequestInfo(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		if requestID != "" {
+			w.Header().Set("X-Request-ID", requestID)
+		}
+
+		ctx := auth.WithRequestID(r.Context(), requestID)
+		ctx = auth.WithClientIP(ctx, clientIP(r))
+		ctx = auth.WithUserAgent(ctx, r.UserAgent())
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+const maxRequestIDLength = 64
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID, or an empty one if there is no
+// randomness, in which case the request is simply not correlated
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
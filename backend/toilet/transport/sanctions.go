@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/endpoint"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func decodeJSONBan(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.BanRequest
+	return decode(r, &req)
+}
+
+func decodeBansUserID(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["userID"], nil
+}
+
+func decodeJSONAppeal(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.AppealRequest
+	return decode(r, &req)
+}
+
+func decodeAppealsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	limit, offset, err := decodePage(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoint.AppealsRequest{Limit: limit, Offset: offset}, nil
+}
+
+func decodeJSONDecideAppeal(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.DecideAppealRequest
+	return decode(r, &req)
+}
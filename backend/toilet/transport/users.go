@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"context"
+	"free_toilet_map/toilet/endpoint"
+	models "free_toilet_map/toilet/model"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Decoder for routes without a request body
+func decodeEmptyRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeJSONProfileUpdate(_ context.Context, r *http.Request) (interface{}, error) {
+	var update models.ProfileUpdate
+	return decode(r, &update)
+}
+
+// Decode user ID from URL
+func decodeUserID(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["userID"], nil
+}
+
+// Decode session ID from URL
+func decodeSessionID(_ context.Context, r *http.Request) (interface{}, error) {
+	return mux.Vars(r)["sessionID"], nil
+}
+
+func decodeJSONSetRole(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoint.SetRoleRequest
+	return decode(r, &req)
+}
+
+func decodeJSONAPIKey(_ context.Context, r *http.Request) (interface{}, error) {
+	var key models.APIKey
+	return decode(r, &key)
+}
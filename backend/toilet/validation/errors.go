@@ -0,0 +1,66 @@
+// Package validation checks user input against the service's policies and
+// reports problems per form field, so that clients can show each message
+// next to the field it belongs to.
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// FieldError is a problem with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"` // stable identifier for clients, e.g. "too_short"
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors. transport.encodeError answers it with
+// 422 and the fields in the problem document.
+type Errors []FieldError
+
+// Add appends a field error
+func (e *Errors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Err returns the errors as an error, or nil if there are none
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// StatusCode implements go-kit's StatusCoder
+func (e Errors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// MarshalJSON implements json.Marshaler; the transport adds the fields to
+// the error body
+func (e Errors) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}{"validation failed", []FieldError(e)})
+}
+
+// Has reports whether there is an error for a field
+func (e Errors) Has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	PasswordMinLength = 8
+	// PasswordMaxBytes is where bcrypt stops reading; anything after it
+	// would silently not count
+	PasswordMaxBytes = 72
+)
+
+// PasswordPolicy checks new passwords
+type PasswordPolicy struct {
+	// BreachedDir holds SHA-1 hash ranges in the Pwned Passwords
+	// k-anonymity format: one file per 5 hex digit prefix (e.g. "5BAA6" or
+	// "5BAA6.txt") with "SUFFIX:COUNT" lines. Empty disables the check.
+	BreachedDir string
+}
+
+// NewPasswordPolicyFromEnv reads BREACHED_PASSWORDS_DIR
+func NewPasswordPolicyFromEnv() *PasswordPolicy {
+	dir := os.Getenv("BREACHED_PASSWORDS_DIR")
+	if dir == "" {
+		log.Println("BREACHED_PASSWORDS_DIR is not set, passwords are not checked against breaches")
+	}
+	return &PasswordPolicy{BreachedDir: dir}
+}
+
+// Check validates a new password. The username is used to reject
+// passwords that merely repeat it.
+func (p *PasswordPolicy) Check(password, username string) Errors {
+	var errs Errors
+
+	switch {
+	case password == "":
+		errs.Add("password", "required", "password is required")
+		return errs
+	case utf8.RuneCountInString(password) < PasswordMinLength:
+		errs.Add("password", "too_short", "password must be at least 8 characters")
+	case len(password) > PasswordMaxBytes:
+		errs.Add("password", "too_long", "password must be at most 72 bytes")
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		errs.Add("password", "same_as_username", "password must not be the same as the username")
+	}
+
+	if p != nil && p.BreachedDir != "" && len(errs) == 0 {
+		breached, err := p.isBreached(password)
+		if err != nil {
+			// A broken hash list must not block registrations
+			log.Printf("breached password check failed: %v", err)
+		} else if breached {
+			errs.Add("password", "breached", "this password appeared in a data breach, please choose another one")
+		}
+	}
+	return errs
+}
+
+// isBreached looks the password's SHA-1 up in the range file of its prefix.
+// Only the matching range is read, never the whole list.
+func (p *PasswordPolicy) isBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(p.BreachedDir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(filepath.Join(p.BreachedDir, prefix+".txt"))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		candidate, count, _ := strings.Cut(line, ":")
+		// Padded responses contain fake entries with a count of 0
+		if strings.TrimSpace(count) == "0" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(candidate), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
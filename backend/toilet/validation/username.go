@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+)
+
+// reservedUsernames may not be registered, nor anything that looks like them
+var reservedUsernames = []string{
+	"admin", "administrator", "moderator", "mod", "root", "system", "support",
+	"help", "staff", "official", "security", "deleted_user", "anonymous",
+	"api", "www", "mail", "null", "undefined", "me", "login", "logout",
+	"register", "settings", "free_toilet_map", "freetoiletmap",
+}
+
+// NormalizeUsername folds compatibility forms such as fullwidth letters into
+// their plain equivalents and trims surrounding space. The result is what
+// gets stored.
+func NormalizeUsername(username string) string {
+	return strings.Map(func(r rune) rune {
+		// Fullwidth ASCII variants, e.g. "ａｄｍｉｎ"
+		if r >= 0xFF01 && r <= 0xFF5E {
+			return r - 0xFEE0
+		}
+		return r
+	}, strings.TrimSpace(username))
+}
+
+// CheckUsername validates a normalized username: length, allowed
+// characters (Latin or Cyrillic letters, digits, "_", "." and "-", without
+// mixing scripts) and reserved names
+func CheckUsername(username string) Errors {
+	var errs Errors
+
+	n := utf8.RuneCountInString(username)
+	switch {
+	case n == 0:
+		errs.Add("username", "required", "username is required")
+		return errs
+	case n < UsernameMinLength:
+		errs.Add("username", "too_short", "username must be at least 3 characters")
+	case n > UsernameMaxLength:
+		errs.Add("username", "too_long", "username must be at most 32 characters")
+	}
+
+	var latin, cyrillic, invalid bool
+	for _, r := range username {
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			latin = true
+		case isRussianLetter(r):
+			cyrillic = true
+		case r < utf8.RuneSelf && (unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'):
+		default:
+			invalid = true
+		}
+	}
+	if invalid {
+		errs.Add("username", "invalid_characters", "username may only contain letters, digits, \"_\", \".\" and \"-\"")
+	} else if latin && cyrillic {
+		errs.Add("username", "mixed_scripts", "username may not mix Latin and Cyrillic letters")
+	}
+
+	first, _ := utf8.DecodeRuneInString(username)
+	last, _ := utf8.DecodeLastRuneInString(username)
+	if isSeparator(first) || isSeparator(last) {
+		errs.Add("username", "invalid_format", "username must start and end with a letter or digit")
+	}
+
+	if IsReservedUsername(username) {
+		errs.Add("username", "reserved", "this username is reserved")
+	}
+	return errs
+}
+
+// IsReservedUsername reports whether a username looks like a reserved one
+func IsReservedUsername(username string) bool {
+	key := UsernameKey(username)
+	for _, reserved := range reservedUsernames {
+		if key == UsernameKey(reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsernameKey returns the skeleton of a username: names that look the same
+// to a reader get the same key. Letters are lowercased, Cyrillic and Greek
+// lookalikes are mapped to Latin, 0 and 1 to o and l, and separators are
+// unified. Two accounts may not share a key.
+func UsernameKey(username string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(NormalizeUsername(username)) {
+		if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// confusables maps characters to the Latin letter they are easily mistaken
+// for (after lowercasing). The map is applied once, so look-alikes of i
+// map to l directly, like i itself.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ь': 'b',
+	'і': 'l', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'з': '3',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Digits and separators
+	'0': 'o', '1': 'l', 'i': 'l', '.': '_', '-': '_',
+}
+
+func isRussianLetter(r rune) bool {
+	return (r >= 'а' && r <= 'я') || (r >= 'А' && r <= 'Я') || r == 'ё' || r == 'Ё'
+}
+
+func isSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-'
+}
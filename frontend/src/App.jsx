@@ -3,10 +3,13 @@ import { ModalsProvider } from "@mantine/modals";
 import Login from "./pages/Login";
 import Register from "./pages/Register";
 import Dashboard from "./pages/Dashboard";
+import ResetPassword from "./pages/ResetPassword";
+import VerifyEmail from "./pages/VerifyEmail";
 import PrivateRoute from "./components/PrivateRoute";
+import { isAuthenticated } from "./auth";
 
 export default function App() {
-  const token = localStorage.getItem("token");
+  const signedIn = isAuthenticated();
 
   return (
     <ModalsProvider>
@@ -14,12 +17,14 @@ export default function App() {
         <Route
           path="/"
           element={
-            token ? <Navigate to="/dashboard" /> : <Navigate to="/login" />
+            signedIn ? <Navigate to="/dashboard" /> : <Navigate to="/login" />
           }
         />
 
         <Route path="/login" element={<Login />} />
         <Route path="/register" element={<Register />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/verify-email" element={<VerifyEmail />} />
 
         <Route
           path="/dashboard"
@@ -1,17 +1,39 @@
 // src/api.js
 import axios from "axios";
-import { getToken } from "./auth";
+import { apiUrl, getCSRFToken, refreshTokens } from "./auth";
 
+// Сессия передаётся в куках, поэтому запросы идут с credentials
 const api = axios.create({
-  baseURL: process.env.VITE_API_URL || "http://localhost:8080",
+  baseURL: apiUrl,
+  withCredentials: true,
 });
 
+// Запросы, изменяющие данные, повторяют CSRF-токен в заголовке
 api.interceptors.request.use((config) => {
-  const token = getToken();
-  if (token) {
-    config.headers.Authorization = `Bearer ${token}`;
+  const csrf = getCSRFToken();
+  const method = (config.method || "get").toUpperCase();
+  if (csrf && !["GET", "HEAD", "OPTIONS"].includes(method)) {
+    config.headers["X-CSRF-Token"] = csrf;
   }
   return config;
 });
 
+// Access-токен живёт недолго: при 401 обновляем его и повторяем запрос
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    if (error.response?.status === 401 && original && !original._retried) {
+      original._retried = true;
+      try {
+        await refreshTokens();
+        return api(original);
+      } catch {
+        // refresh-токен недействителен, отдаём исходную ошибку
+      }
+    }
+    return Promise.reject(error);
+  }
+);
+
 export default api;
@@ -0,0 +1,29 @@
+// src/appeal.js
+import api from "./api";
+
+// Блокировку можно один раз обжаловать. Личность подтверждает токен,
+// который выдаётся при попытке входа, а без него — имя и пароль.
+export async function offerAppeal(ban, credentials = {}) {
+  const until = ban.expires_at
+    ? `до ${new Date(ban.expires_at).toLocaleString()}`
+    : "навсегда";
+  const text = window.prompt(
+    `Аккаунт заблокирован ${until}.\nПричина: ${ban.reason}\n\n` +
+      "Если вы не согласны, опишите, почему блокировку стоит снять:"
+  );
+  if (!text) return;
+  try {
+    await api.post(
+      "/appeal",
+      ban.appeal_token
+        ? { appeal_token: ban.appeal_token, text }
+        : { ...credentials, text }
+    );
+    alert("Апелляция отправлена модераторам");
+  } catch (err) {
+    alert(
+      "Не удалось отправить апелляцию: " +
+        (err.response?.data?.error ?? err.message)
+    );
+  }
+}
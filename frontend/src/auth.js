@@ -1,16 +1,55 @@
 // src/auth.js
-export function saveToken(token) {
-  localStorage.setItem("token", token);
+// Токены сессии бэкенд хранит в HttpOnly-куках (режим "X-Session-Mode:
+// cookie"), недоступных скриптам. Здесь хранятся только CSRF-токен, который
+// повторяется в заголовке X-CSRF-Token, и время окончания сессии.
+export const apiUrl = process.env.VITE_API_URL || "http://localhost:8080";
+
+// Заголовки запросов, которые выдают токены: /login, /login/2fa и
+// /token/refresh
+export const sessionHeaders = {
+  "Content-Type": "application/json",
+  "X-Session-Mode": "cookie",
+};
+
+export function getCSRFToken() {
+  return localStorage.getItem("csrf_token");
+}
+
+// Сохраняет CSRF-токен и срок сессии из ответа /login или /token/refresh
+export function saveSession(data) {
+  localStorage.setItem("csrf_token", data.csrf_token);
+  const expiresIn = Number(data.refresh_expires_in || data.expires_in);
+  localStorage.setItem(
+    "session_expires_at",
+    String(Date.now() + expiresIn * 1000)
+  );
 }
 
-export function getToken() {
-  return localStorage.getItem("token");
+// Обновляет сессию: refresh-токен браузер отправляет в куке сам
+export async function refreshTokens() {
+  const csrf = getCSRFToken();
+  if (!csrf) {
+    throw new Error("no session");
+  }
+
+  const res = await fetch(`${apiUrl}/token/refresh`, {
+    method: "POST",
+    credentials: "include",
+    headers: { ...sessionHeaders, "X-CSRF-Token": csrf },
+  });
+  if (!res.ok) {
+    logout();
+    throw new Error("refresh failed");
+  }
+
+  saveSession(await res.json());
 }
 
 export function isAuthenticated() {
-  return !!getToken();
+  return Number(localStorage.getItem("session_expires_at")) > Date.now();
 }
 
 export function logout() {
-  localStorage.removeItem("token");
+  localStorage.removeItem("csrf_token");
+  localStorage.removeItem("session_expires_at");
 }
@@ -28,6 +28,25 @@ export function ModalContent({ toilet, userId, onSubmit, onDelete, onClose }) {
   }, [toilet.id]);
 
   const isOwner = toilet.founder_id === userId;
+  const isPending = toilet.approval === "pending";
+  const [confirmMessage, setConfirmMessage] = useState(null);
+
+  // Подтверждение непроверенного туалета другим пользователем
+  const confirmToilet = async () => {
+    try {
+      const response = await api.post("/toilet/confirm", { id: toilet.id });
+      if (response.data.approval === "approved") {
+        setConfirmMessage("Спасибо! Туалет подтверждён");
+      } else {
+        setConfirmMessage(
+          `Спасибо! Подтверждений: ${response.data.confirmations} из ${response.data.required}`
+        );
+      }
+    } catch (err) {
+      setConfirmMessage("Не удалось подтвердить туалет");
+      console.error(err);
+    }
+  };
 
   const confirmDelete = () => {
     const confirmed = window.confirm(
@@ -49,6 +68,14 @@ export function ModalContent({ toilet, userId, onSubmit, onDelete, onClose }) {
     <div className="space-y-4">
       {/* Toilet information */}
       <div className="bg-gray-50 rounded-lg p-4 space-y-2">
+        {isPending && (
+          <p className="text-sm font-medium text-yellow-700">
+            Не проверен: ждёт подтверждения
+          </p>
+        )}
+        {toilet.remote && (
+          <p className="text-sm text-yellow-700">Добавлен не на месте</p>
+        )}
         <p className="text-sm text-gray-600">
           <span className="font-medium">Координаты:</span> {toilet.point}
         </p>
@@ -141,6 +168,19 @@ export function ModalContent({ toilet, userId, onSubmit, onDelete, onClose }) {
             Отправить отзыв
           </button>
 
+          {isPending && !isOwner && (
+            <button
+              onClick={confirmToilet}
+              className="w-full bg-green-600 hover:bg-green-700 text-white font-medium py-2 px-4 rounded-md transition-colors duration-200"
+            >
+              Я был здесь, туалет существует
+            </button>
+          )}
+
+          {confirmMessage && (
+            <p className="text-sm text-gray-600">{confirmMessage}</p>
+          )}
+
           {isOwner && (
             <button
               onClick={confirmDelete}
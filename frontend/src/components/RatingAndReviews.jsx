@@ -12,6 +12,11 @@ export function RatingAndReviews({ reviews }) {
             <h4 className="font-medium text-gray-900">{review.title}</h4>
             <p className="text-sm text-gray-700 my-3">{review.review_text}</p>
             <p className="text-sm text-gray-500">Оценка: {review.score}</p>
+            {review.remote && (
+              <p className="text-xs text-yellow-700 mt-1">
+                Отзыв оставлен не на месте
+              </p>
+            )}
             <p className="text-xs text-gray-500 mt-3">
               <strong>Дата отзыва:</strong>{" "}
               {new Date(review.created_at).toLocaleString()}
@@ -1,27 +1,36 @@
-import { useEffect, useState } from "react";
+import { useCallback, useEffect, useState } from "react";
+import api from "../api";
+import {
+  isAuthenticated as hasSession,
+  logout as clearSession,
+  saveSession,
+} from "../auth";
 
 export function useAuth() {
-  const [token, setToken] = useState(localStorage.getItem("token"));
+  const [isAuthenticated, setAuthenticated] = useState(hasSession());
 
   useEffect(() => {
     const handleStorage = () => {
-      setToken(localStorage.getItem("token"));
+      setAuthenticated(hasSession());
     };
     window.addEventListener("storage", handleStorage);
     return () => window.removeEventListener("storage", handleStorage);
   }, []);
 
-  const login = (token) => {
-    localStorage.setItem("token", token);
-    setToken(token);
-  };
-
-  const logout = () => {
-    localStorage.removeItem("token");
-    setToken(null);
-  };
+  const login = useCallback((data) => {
+    saveSession(data);
+    setAuthenticated(true);
+  }, []);
 
-  const isAuthenticated = !!token;
+  const logout = useCallback(async () => {
+    setAuthenticated(false);
+    if (hasSession()) {
+      // Отзываем сессию на сервере, он же удаляет куки; локальные данные
+      // удаляем в любом случае
+      await api.post("/logout").catch(() => {});
+    }
+    clearSession();
+  }, []);
 
-  return { token, login, logout, isAuthenticated };
+  return { login, logout, isAuthenticated };
 }
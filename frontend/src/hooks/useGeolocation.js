@@ -11,6 +11,7 @@ export function useGeolocation() {
           setPosition({
             lat: pos.coords.latitude,
             lng: pos.coords.longitude,
+            accuracy: pos.coords.accuracy,
           });
         },
         (err) => {
@@ -8,6 +8,9 @@ import App from "./App";
 import Login from "./pages/Login";
 import Register from "./pages/Register";
 import Dashboard from "./pages/Dashboard";
+import OAuthCallback from "./pages/OAuthCallback";
+import ResetPassword from "./pages/ResetPassword";
+import VerifyEmail from "./pages/VerifyEmail";
 import "@mantine/core/styles.css";
 import "./index.css";
 
@@ -22,6 +25,11 @@ ReactDOM.createRoot(document.getElementById("root")).render(
               <Route path="login" element={<Login />} />
               <Route path="register" element={<Register />} />
             </Route>
+            {/* Возврат после входа через внешний сервис */}
+            <Route path="/oauth/callback" element={<OAuthCallback />} />
+            {/* Ссылки из писем: сброс пароля и подтверждение почты */}
+            <Route path="/reset-password" element={<ResetPassword />} />
+            <Route path="/verify-email" element={<VerifyEmail />} />
             {/* Маршрут для dashboard */}
             <Route path="/dashboard" element={<Dashboard />} />
           </Routes>
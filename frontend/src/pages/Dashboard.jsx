@@ -1,14 +1,34 @@
 import { useEffect, useState } from "react";
 import api from "../api";
 import { useAuth } from "../hooks/useAuth";
-import { parseJwt } from "../utils";
 import MapContainer from "../components/ToiletMap";
 import { ModalAddToilet } from "../components/ModalAddToilet";
 import { ModalContent } from "../components/ModalContent";
 import { useGeolocation } from "../hooks/useGeolocation";
 
+// Сообщения фильтра контента (ответ 422 с причинами отказа)
+const screeningReasons = {
+  profanity: "текст содержит нецензурную лексику",
+  duplicate: "такой отзыв уже был опубликован",
+};
+
+// Местоположение устройства для проверки, что отзыв или туалет
+// добавлены на месте
+function deviceLocation(position) {
+  if (!position) return undefined;
+  return { lat: position.lat, lng: position.lng, accuracy: position.accuracy };
+}
+
+function screeningMessage(error) {
+  const reasons = error.response?.data?.reasons;
+  if (error.response?.status === 422 && reasons) {
+    return reasons.map((r) => screeningReasons[r] ?? r).join(", ");
+  }
+  return error.message;
+}
+
 export default function Dashboard() {
-  const { token, logout } = useAuth();
+  const { isAuthenticated, logout } = useAuth();
   const { position, error: geoError } = useGeolocation();
   const [toilets, setToilets] = useState([]);
   const [loading, setLoading] = useState(true);
@@ -19,22 +39,24 @@ export default function Dashboard() {
   const [modalContent, setModalContent] = useState(null);
   const [mapInstance, setMapInstance] = useState(null);
 
-  // Проверка токена и его валидности
+  // Проверка сессии: токены в HttpOnly-куках, поэтому пользователь
+  // запрашивается у сервера. Просроченный access-токен api.js обновляет сам.
   useEffect(() => {
-    if (!token) {
+    if (!isAuthenticated) {
       window.location.href = "/login";
       return;
     }
 
-    const decodedToken = parseJwt(token);
-    if (!decodedToken || decodedToken.exp * 1000 < Date.now()) {
-      logout();
-      window.location.href = "/login";
-      return;
-    }
-
-    setUserId(decodedToken.user_id);
-  }, [token, logout]);
+    api
+      .get("/me")
+      .then((res) => setUserId(res.data.id))
+      .catch(async (error) => {
+        if (error.response?.status === 401) {
+          await logout();
+          window.location.href = "/login";
+        }
+      });
+  }, [isAuthenticated, logout]);
 
   // Загружаем список туалетов
   useEffect(() => {
@@ -66,19 +88,14 @@ export default function Dashboard() {
     toiletType
   ) => {
     try {
-      const response = await api.post(
-        "/toilet/add",
-        {
-          name,
-          point: `${lat},${lng}`,
-          gender: toiletGender,
-          type: toiletType,
-          address: address,
-        },
-        {
-          headers: { Authorization: `Bearer ${token}` },
-        }
-      );
+      const response = await api.post("/toilet/add", {
+        name,
+        point: `${lat},${lng}`,
+        gender: toiletGender,
+        type: toiletType,
+        address: address,
+        location: deviceLocation(position),
+      });
 
       const toilet = {
         ...response.data,
@@ -87,10 +104,20 @@ export default function Dashboard() {
         id: response.data.id ?? Date.now(),
       };
 
-      setToilets((prev) => [...prev, toilet]);
       setShowModal(false);
+      if (response.data.status === "pending") {
+        setError("Туалет отправлен на проверку модератору");
+        return;
+      }
+      if (response.data.approval === "pending") {
+        setError(
+          "Туалет появится на карте после подтверждения другими пользователями или модератором"
+        );
+        return;
+      }
+      setToilets((prev) => [...prev, toilet]);
     } catch (error) {
-      setError("Ошибка при добавлении туалета: " + error.message);
+      setError("Ошибка при добавлении туалета: " + screeningMessage(error));
     }
   };
 
@@ -99,7 +126,6 @@ export default function Dashboard() {
     try {
       await api.delete("/toilet/delete", {
         data: { id },
-        headers: { Authorization: `Bearer ${token}` },
       });
 
       setToilets((prev) => prev.filter((t) => t.id !== id));
@@ -123,23 +149,21 @@ export default function Dashboard() {
   const submitReview = async (toiletId, title, text, score) => {
     if (!title || !text || score === null) return;
     try {
-      await api.post(
-        "/review/add",
-        {
-          toilet_id: toiletId,
-          title,
-          review_text: text,
-          score,
-        },
-        {
-          headers: { Authorization: `Bearer ${token}` },
-        }
-      );
+      const response = await api.post("/review/add", {
+        toilet_id: toiletId,
+        title,
+        review_text: text,
+        score,
+        location: deviceLocation(position),
+      });
 
       setShowModal(false);
       setActiveToilet(null);
+      if (response.data.status === "pending") {
+        setError("Отзыв будет опубликован после проверки модератором");
+      }
     } catch (error) {
-      setError("Ошибка при отправке отзыва: " + error.message);
+      setError("Ошибка при отправке отзыва: " + screeningMessage(error));
     }
   };
 
@@ -1,38 +1,94 @@
 import { useState, useRef, useEffect } from "react";
-import { useNavigate } from "react-router-dom";
+import { useLocation, useNavigate } from "react-router-dom";
 import * as THREE from "three";
 import { GLTFLoader } from "three/examples/jsm/loaders/GLTFLoader";
 import api from "../api";
+import { apiUrl, saveSession, sessionHeaders } from "../auth";
+import { solveChallenge } from "../pow";
+import { offerAppeal } from "../appeal";
 
 export default function Login() {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [loading, setLoading] = useState(false);
+  const [providers, setProviders] = useState([]);
+  const location = useLocation();
+  // Токен второго шага входа, если у пользователя включена 2FA
+  const [challenge, setChallenge] = useState(location.state?.challenge || "");
+  const [code, setCode] = useState("");
   const navigate = useNavigate();
   const canvasRef = useRef(null);
   const animationRef = useRef(null); // Для хранения requestAnimationFrame ID
 
-  const handleLogin = async () => {
-    const apiUrl = process.env.VITE_API_URL || "http://localhost:8080";
+  // Список внешних сервисов для входа ("Войти через...")
+  useEffect(() => {
+    api
+      .get("/oidc/providers")
+      .then((res) => setProviders(res.data.providers || []))
+      .catch(() => setProviders([]));
+  }, []);
 
-    if (!username || !password) {
-      alert("Введите имя и пароль");
+  const handleLogin = async () => {
+    if (challenge ? !code : !username || !password) {
+      alert(challenge ? "Введите код подтверждения" : "Введите имя и пароль");
       return;
     }
 
     setLoading(true);
 
     try {
-      const res = await fetch(`${apiUrl}/login`, {
-        method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ username, password }),
-      });
-
-      if (!res.ok) throw new Error("Ошибка входа");
+      // Токены сессии сервер установит в HttpOnly-куках
+      const res = challenge
+        ? await fetch(`${apiUrl}/login/2fa`, {
+            method: "POST",
+            credentials: "include",
+            headers: sessionHeaders,
+            body: JSON.stringify({ challenge_token: challenge, code }),
+          })
+        : await fetch(`${apiUrl}/login`, {
+            method: "POST",
+            credentials: "include",
+            headers: sessionHeaders,
+            body: JSON.stringify({
+              username,
+              password,
+              ...(await solveChallenge("login")),
+            }),
+          });
+
+      if (res.status === 429) {
+        throw new Error("Слишком много неудачных попыток. Попробуйте позже");
+      }
+      if (res.status === 403) {
+        const body = await res.json().catch(() => ({}));
+        if (body.challenge_required) {
+          throw new Error("Проверка не пройдена. Попробуйте ещё раз");
+        }
+        if (body.kind) {
+          await offerAppeal(body, { username, password });
+          return;
+        }
+        throw new Error("Аккаунт заблокирован модератором");
+      }
+      if (!res.ok) {
+        if (challenge) {
+          const body = await res.json().catch(() => ({}));
+          if (body.error === "login challenge expired, sign in again") {
+            setChallenge("");
+            setCode("");
+            throw new Error("Время на ввод кода истекло. Войдите заново");
+          }
+          throw new Error("Неверный код подтверждения");
+        }
+        throw new Error("Неверное имя пользователя или пароль");
+      }
 
       const data = await res.json();
-      localStorage.setItem("token", data.token);
+      if (data.mfa_required) {
+        setChallenge(data.challenge_token);
+        return;
+      }
+      saveSession(data);
 
       // Останавливаем анимацию перед переходом
       if (animationRef.current) {
@@ -231,33 +287,52 @@ export default function Login() {
           </h2>
 
           <div className="flex flex-col space-y-4">
-            <div className="space-y-2">
-              <label className="block text-white text-sm font-medium">
-                Имя пользователя
-              </label>
-              <input
-                type="text"
-                placeholder="Введите ваше имя"
-                value={username}
-                onChange={(e) => setUsername(e.target.value)}
-                required
-                className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
-              />
-            </div>
+            {challenge ? (
+              <div className="space-y-2">
+                <label className="block text-white text-sm font-medium">
+                  Код из приложения или код восстановления
+                </label>
+                <input
+                  type="text"
+                  autoComplete="one-time-code"
+                  placeholder="123456"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                  className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                />
+              </div>
+            ) : (
+              <>
+                <div className="space-y-2">
+                  <label className="block text-white text-sm font-medium">
+                    Имя пользователя
+                  </label>
+                  <input
+                    type="text"
+                    placeholder="Введите ваше имя"
+                    value={username}
+                    onChange={(e) => setUsername(e.target.value)}
+                    required
+                    className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                  />
+                </div>
 
-            <div className="space-y-2">
-              <label className="block text-white text-sm font-medium">
-                Пароль
-              </label>
-              <input
-                type="password"
-                placeholder="Введите ваш пароль"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-                className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
-              />
-            </div>
+                <div className="space-y-2">
+                  <label className="block text-white text-sm font-medium">
+                    Пароль
+                  </label>
+                  <input
+                    type="password"
+                    placeholder="Введите ваш пароль"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    required
+                    className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                  />
+                </div>
+              </>
+            )}
 
             <div className="flex justify-between items-center mb-4">
               <button
@@ -286,6 +361,18 @@ export default function Login() {
                 "Войти"
               )}
             </button>
+
+            {providers.map((provider) => (
+              <a
+                key={provider}
+                href={`${apiUrl}/oidc/${encodeURIComponent(
+                  provider
+                )}/login?session=cookie`}
+                className="w-full py-3 px-4 rounded-lg font-bold text-center text-white border border-white border-opacity-30 hover:bg-white hover:bg-opacity-20 transition-colors duration-200"
+              >
+                Войти через {provider}
+              </a>
+            ))}
           </div>
         </div>
       </div>
@@ -0,0 +1,68 @@
+import { useEffect } from "react";
+import { useNavigate } from "react-router-dom";
+import { saveSession } from "../auth";
+import { offerAppeal } from "../appeal";
+
+// Сюда бэкенд возвращает пользователя после входа через внешний сервис.
+// Вход начинается в режиме кук: токены сессии сервер устанавливает в
+// HttpOnly-куках, а во фрагменте URL передаются только CSRF-токен и сроки,
+// которые сразу из него удаляются.
+export default function OAuthCallback() {
+  const navigate = useNavigate();
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, "", window.location.pathname);
+
+    const error = params.get("error");
+    // Повторный вход через сервис вместо пароля: токен подтверждает
+    // удаление аккаунта и хранится только до конца сессии браузера
+    if (params.get("reauth_token")) {
+      sessionStorage.setItem("reauth_token", params.get("reauth_token"));
+      navigate("/dashboard");
+      return;
+    }
+    // Аккаунт заблокирован: вход через сервис подтвердил владельца, и
+    // блокировку можно обжаловать
+    if (params.get("appeal_token")) {
+      offerAppeal(Object.fromEntries(params)).then(() => navigate("/login"));
+      return;
+    }
+    if (error) {
+      // Подробности внутренних ошибок бэкенд не сообщает
+      alert(
+        error === "server_error"
+          ? "Не удалось войти. Попробуйте позже"
+          : `Ошибка входа: ${error}`
+      );
+      navigate("/login");
+      return;
+    }
+
+    // Включена двухфакторная аутентификация: код вводится на странице входа
+    if (params.get("mfa_required")) {
+      navigate("/login", {
+        state: { challenge: params.get("challenge_token") },
+      });
+      return;
+    }
+
+    if (params.get("session") !== "cookie") {
+      alert("Не удалось войти. Попробуйте позже");
+      navigate("/login");
+      return;
+    }
+    saveSession({
+      csrf_token: params.get("csrf_token"),
+      expires_in: params.get("expires_in"),
+      refresh_expires_in: params.get("refresh_expires_in"),
+    });
+    navigate("/dashboard");
+  }, [navigate]);
+
+  return (
+    <div className="flex min-h-screen justify-center items-center">
+      Выполняется вход...
+    </div>
+  );
+}
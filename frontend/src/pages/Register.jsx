@@ -2,11 +2,45 @@ import { useState, useRef, useEffect } from "react";
 import { useNavigate } from "react-router-dom";
 import * as THREE from "three";
 import { GLTFLoader } from "three/examples/jsm/loaders/GLTFLoader";
+import { solveChallenge } from "../pow";
+
+// Тексты ошибок проверки по их кодам; для остальных показываем сообщение сервера
+const errorMessages = {
+  required: "Обязательное поле",
+  too_short: {
+    username: "Имя должно быть не короче 3 символов",
+    password: "Пароль должен быть не короче 8 символов",
+  },
+  too_long: {
+    username: "Имя должно быть не длиннее 32 символов",
+    password: "Пароль слишком длинный (не более 72 байт)",
+  },
+  invalid_characters: "Допустимы буквы, цифры и символы «_», «.», «-»",
+  mixed_scripts: "Нельзя смешивать латиницу и кириллицу",
+  invalid_format: "Имя должно начинаться и заканчиваться буквой или цифрой",
+  reserved: "Это имя зарезервировано",
+  taken: {
+    username: "Это имя уже занято",
+    email: "Эта почта уже используется",
+  },
+  same_as_username: "Пароль не должен совпадать с именем",
+  breached: "Этот пароль встречался в утечках данных, выберите другой",
+  invalid: "Некорректное значение",
+};
+
+function fieldErrorText({ field, code, message }) {
+  const text = errorMessages[code];
+  if (typeof text === "string") return text;
+  return (text && text[field]) || message;
+}
 
 export default function Register() {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
+  const [email, setEmail] = useState("");
   const [loading, setLoading] = useState(false);
+  // Ошибки по полям формы из ответа сервера: { username: "...", ... }
+  const [fieldErrors, setFieldErrors] = useState({});
   const navigate = useNavigate();
   const canvasRef = useRef(null);
   const animationRef = useRef(null); // Для хранения requestAnimationFrame ID
@@ -25,12 +59,34 @@ export default function Register() {
       const res = await fetch(`${apiUrl}/user/create`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ username, password }),
+        body: JSON.stringify({
+          username,
+          password,
+          email,
+          ...(await solveChallenge("register")),
+        }),
       });
 
+      if (res.status === 422) {
+        const body = await res.json();
+        const errors = {};
+        for (const fe of body.fields || []) {
+          errors[fe.field] = errors[fe.field] || fieldErrorText(fe);
+        }
+        setFieldErrors(errors);
+        return;
+      }
+      if (res.status === 403) {
+        throw new Error("Проверка не пройдена. Попробуйте ещё раз");
+      }
       if (!res.ok) throw new Error("Ошибка регистрации");
+      setFieldErrors({});
 
-      alert("Пользователь создан");
+      alert(
+        email
+          ? "Пользователь создан. Мы отправили письмо для подтверждения почты"
+          : "Пользователь создан"
+      );
 
       navigate("/login");
     } catch (error) {
@@ -236,6 +292,25 @@ export default function Register() {
                 required
                 className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
               />
+              {fieldErrors.username && (
+                <p className="text-red-400 text-sm">{fieldErrors.username}</p>
+              )}
+            </div>
+
+            <div className="space-y-2">
+              <label className="block text-white text-sm font-medium">
+                Электронная почта (для восстановления пароля)
+              </label>
+              <input
+                type="email"
+                placeholder="Введите вашу почту"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+              />
+              {fieldErrors.email && (
+                <p className="text-red-400 text-sm">{fieldErrors.email}</p>
+              )}
             </div>
 
             <div className="space-y-2">
@@ -250,6 +325,9 @@ export default function Register() {
                 required
                 className="w-full px-4 py-3 rounded-lg bg-white bg-opacity-20 border border-white border-opacity-30 text-white placeholder-white placeholder-opacity-70 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent"
               />
+              {fieldErrors.password && (
+                <p className="text-red-400 text-sm">{fieldErrors.password}</p>
+              )}
             </div>
 
             <button
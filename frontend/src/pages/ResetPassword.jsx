@@ -0,0 +1,103 @@
+import { useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import api from "../api";
+
+// Тексты ошибок проверки пароля по их кодам; для остальных показываем
+// сообщение сервера
+const passwordErrors = {
+  required: "Введите новый пароль",
+  too_short: "Пароль должен быть не короче 8 символов",
+  too_long: "Пароль слишком длинный (не более 72 байт)",
+  breached: "Этот пароль встречался в утечках данных, выберите другой",
+};
+
+// Сюда ведёт ссылка из письма для сброса пароля: /reset-password?token=...
+export default function ResetPassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token") || "";
+  const [password, setPassword] = useState("");
+  const [repeat, setRepeat] = useState("");
+  const [error, setError] = useState("");
+  const [loading, setLoading] = useState(false);
+  const navigate = useNavigate();
+
+  const handleReset = async () => {
+    if (!password) {
+      setError(passwordErrors.required);
+      return;
+    }
+    if (password !== repeat) {
+      setError("Пароли не совпадают");
+      return;
+    }
+
+    setLoading(true);
+    setError("");
+    try {
+      await api.post("/password/reset", { token, password });
+      alert("Пароль изменён. Войдите с новым паролем");
+      navigate("/login");
+    } catch (err) {
+      const body = err.response?.data ?? {};
+      const field = (body.fields || [])[0];
+      setError(
+        field
+          ? passwordErrors[field.code] || field.message
+          : body.error === "invalid or expired token"
+          ? "Ссылка недействительна или устарела. Запросите сброс пароля ещё раз"
+          : body.error ?? err.message
+      );
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (!token) {
+    return (
+      <div className="flex min-h-screen justify-center items-center">
+        Ссылка для сброса пароля неполная
+      </div>
+    );
+  }
+
+  return (
+    <div className="flex min-h-screen justify-center items-center">
+      <div className="container mx-auto px-4 max-w-xs">
+        <h2 className="text-2xl font-bold text-center mb-8">Новый пароль</h2>
+
+        <div className="flex flex-col space-y-4">
+          <input
+            type="password"
+            autoComplete="new-password"
+            placeholder="Новый пароль"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            className="w-full px-4 py-3 rounded-lg border focus:outline-none focus:ring-2 focus:ring-blue-500"
+          />
+          <input
+            type="password"
+            autoComplete="new-password"
+            placeholder="Повторите пароль"
+            value={repeat}
+            onChange={(e) => setRepeat(e.target.value)}
+            className="w-full px-4 py-3 rounded-lg border focus:outline-none focus:ring-2 focus:ring-blue-500"
+          />
+
+          {error && <p className="text-red-500 text-sm">{error}</p>}
+
+          <button
+            onClick={handleReset}
+            disabled={loading}
+            className={`w-full py-3 px-4 rounded-lg font-bold transition-colors duration-200 ${
+              loading
+                ? "bg-gray-400 cursor-not-allowed"
+                : "bg-blue-500 hover:bg-blue-600 text-white"
+            }`}
+          >
+            {loading ? "Сохранение..." : "Сохранить пароль"}
+          </button>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,43 @@
+import { useEffect, useRef, useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
+import api from "../api";
+
+// Сюда ведёт ссылка из письма для подтверждения почты:
+// /verify-email?token=...
+export default function VerifyEmail() {
+  const [searchParams] = useSearchParams();
+  const [status, setStatus] = useState("pending");
+  // Токен одноразовый, поэтому отправляем его только один раз, даже если
+  // эффект вызывается повторно (StrictMode)
+  const sent = useRef(false);
+
+  useEffect(() => {
+    if (sent.current) return;
+    sent.current = true;
+
+    const token = searchParams.get("token");
+    if (!token) {
+      setStatus("failed");
+      return;
+    }
+    api
+      .post("/email/verify", { token })
+      .then(() => setStatus("verified"))
+      .catch(() => setStatus("failed"));
+  }, [searchParams]);
+
+  return (
+    <div className="flex flex-col min-h-screen justify-center items-center space-y-4">
+      {status === "pending" && <p>Подтверждаем почту...</p>}
+      {status === "verified" && <p>Почта подтверждена</p>}
+      {status === "failed" && (
+        <p>Ссылка недействительна или устарела. Запросите новое письмо</p>
+      )}
+      {status !== "pending" && (
+        <Link to="/" className="text-blue-500 hover:text-blue-600">
+          На главную
+        </Link>
+      )}
+    </div>
+  );
+}
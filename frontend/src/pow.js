@@ -0,0 +1,35 @@
+// src/pow.js
+import api from "./api";
+
+// Число ведущих нулевых битов в хэше
+function leadingZeroBits(bytes) {
+  let bits = 0;
+  for (const b of bytes) {
+    if (b === 0) {
+      bits += 8;
+      continue;
+    }
+    return bits + Math.clz32(b) - 24;
+  }
+  return bits;
+}
+
+// Получает задачу proof-of-work для регистрации ("register") или входа
+// ("login") и подбирает nonce, при котором SHA-256(challenge + ":" + nonce)
+// начинается с нужного числа нулевых битов. Возвращает поля, которые
+// отправляются вместе с формой; если проверка отключена — пустой объект.
+export async function solveChallenge(purpose) {
+  const { data } = await api.get("/challenge", { params: { purpose } });
+  if (!data.difficulty) return {};
+
+  const encoder = new TextEncoder();
+  for (let nonce = 0; ; nonce++) {
+    const hash = await crypto.subtle.digest(
+      "SHA-256",
+      encoder.encode(`${data.challenge}:${nonce}`)
+    );
+    if (leadingZeroBits(new Uint8Array(hash)) >= data.difficulty) {
+      return { challenge: data.challenge, nonce: String(nonce) };
+    }
+  }
+}